@@ -0,0 +1,70 @@
+// Command create-admin creates the first super-admin, or makes an existing user one.
+//
+//	go run ./cmd/create-admin -email admin@example.com -password 's3cret!'
+//
+// It uses the same database settings as the API server. Further staff are invited through the API.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/rbac"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func main() {
+	email := flag.String("email", "", "email address of the admin")
+	password := flag.String("password", "", "password for a new account (ignored if the user exists)")
+	flag.Parse()
+
+	if *email == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := config.DB.AutoMigrate(&models.Role{}, &models.RolePermission{}, &models.User{}); err != nil {
+		log.Fatal("Migration failed: ", err)
+	}
+	if err := rbac.Seed(config.DB); err != nil {
+		log.Fatal("Seeding roles failed: ", err)
+	}
+
+	var user models.User
+	err := config.DB.Where("email = ?", *email).First(&user).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if len(*password) < 6 {
+			log.Fatal("A new account needs a -password of at least 6 characters")
+		}
+		hashedPwd, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Fatal("Unable to hash password: ", err)
+		}
+		now := time.Now()
+		user = models.User{
+			Email:           *email,
+			Password:        string(hashedPwd),
+			EmailVerified:   true,
+			EmailVerifiedAt: &now,
+		}
+		if err := config.DB.Create(&user).Error; err != nil {
+			log.Fatal("Unable to create user: ", err)
+		}
+		fmt.Printf("Created user %s\n", user.Email)
+	case err != nil:
+		log.Fatal("Looking up user failed: ", err)
+	}
+
+	if err := rbac.GrantSuperAdmin(config.DB, &user); err != nil {
+		log.Fatal("Granting super-admin failed: ", err)
+	}
+	fmt.Printf("%s is now a %s\n", user.Email, rbac.SuperAdmin)
+}
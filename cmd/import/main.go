@@ -0,0 +1,78 @@
+// Command import bulk-loads products from a CSV or JSON Lines file, upserting them by SKU.
+//
+//	go run ./cmd/import -file products.csv [-format csv|jsonl] [-dry-run]
+//
+// It uses the same database settings as the API server and records the run as an import job.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/importer"
+	"github.com/Emibrown/E-commerce-API/models"
+)
+
+func main() {
+	path := flag.String("file", "", "path to the CSV or JSON Lines file to import")
+	formatName := flag.String("format", "", "csv or jsonl (defaults to the file extension)")
+	dryRun := flag.Bool("dry-run", false, "validate and match rows without writing anything")
+	flag.Parse()
+
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var format importer.Format
+	var err error
+	if *formatName != "" {
+		if format, err = importer.ParseFormat(*formatName); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		var ok bool
+		if format, ok = importer.FormatFromFilename(*path); !ok {
+			log.Fatal("Could not tell the file format; pass -format csv or -format jsonl")
+		}
+	}
+
+	data, err := os.ReadFile(*path)
+	if err != nil {
+		log.Fatal("Could not read file: ", err)
+	}
+
+	if err := config.DB.AutoMigrate(&models.ImportJob{}); err != nil {
+		log.Fatal("Migration failed: ", err)
+	}
+
+	job := models.ImportJob{
+		Filename: *path,
+		Format:   string(format),
+		DryRun:   *dryRun,
+		Status:   models.ImportQueued,
+	}
+	if err := config.DB.Create(&job).Error; err != nil {
+		log.Fatal("Could not create import job: ", err)
+	}
+
+	if err := importer.Run(config.DB, &job, data); err != nil {
+		log.Fatalf("Import job %d failed: %v", job.ID, err)
+	}
+
+	if job.DryRun {
+		fmt.Println("Dry run, nothing was written.")
+	}
+	fmt.Printf("Import job %d: %d rows, %d created, %d updated, %d failed\n",
+		job.ID, job.TotalRows, job.CreatedCount, job.UpdatedCount, job.FailedCount)
+	for _, e := range job.Errors {
+		fmt.Printf("  row %d (sku %q): %s\n", e.Row, e.SKU, e.Error)
+	}
+
+	if job.FailedCount > 0 {
+		os.Exit(1)
+	}
+}
@@ -7,8 +7,15 @@ import (
 
 	"github.com/Emibrown/E-commerce-API/config"
 	"github.com/Emibrown/E-commerce-API/docs"
+	"github.com/Emibrown/E-commerce-API/importer"
+	"github.com/Emibrown/E-commerce-API/jwtkeys"
+	"github.com/Emibrown/E-commerce-API/loginguard"
+	"github.com/Emibrown/E-commerce-API/mailer"
 	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/pricing"
+	"github.com/Emibrown/E-commerce-API/rbac"
 	"github.com/Emibrown/E-commerce-API/routes"
+	"github.com/Emibrown/E-commerce-API/storage"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -38,19 +45,122 @@ import (
 // @produce json
 
 func main() {
+	// Accounts that exist before email verification is introduced are trusted as they are
+	grandfatherEmails := !config.DB.Migrator().HasColumn(&models.User{}, "EmailVerified")
+	// Orders placed before totals were stored need theirs computed once
+	backfillTotals := config.DB.Migrator().HasTable(&models.Order{}) && !config.DB.Migrator().HasColumn(&models.Order{}, "Total")
+
 	// Auto-migrate models
 	err := config.DB.AutoMigrate(
+		&models.CustomerGroup{},
+		&models.Role{},
+		&models.RolePermission{},
+		&models.StaffInvitation{},
 		&models.User{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.TwoFactorChallenge{},
+		&models.LoginAttemptCounter{},
+		&models.LoginFailure{},
 		&models.Product{},
+		&models.ProductImage{},
+		&models.ProductPriceTier{},
+		&models.PriceHistory{},
+		&models.ScheduledPrice{},
+		&models.Review{},
+		&models.Wishlist{},
+		&models.WishlistItem{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderStatusHistory{},
+		&models.IdempotencyKey{},
+		&models.ImportJob{},
 	)
 	if err != nil {
 		log.Fatal("Migration failed:", err)
 	}
 
+	if err := rbac.Seed(config.DB); err != nil {
+		log.Fatal("Seeding roles failed:", err)
+	}
+
+	// Admins from before roles existed become super-admins
+	if config.DB.Migrator().HasColumn("users", "is_admin") {
+		err = config.DB.Exec(`INSERT INTO user_roles (user_id, role_id)
+			SELECT users.id, roles.id FROM users, roles
+			WHERE users.is_admin AND roles.name = ?
+			ON CONFLICT DO NOTHING`, rbac.SuperAdmin).Error
+		if err == nil {
+			err = config.DB.Migrator().DropColumn("users", "is_admin")
+		}
+		if err != nil {
+			log.Fatal("Migrating admins to roles failed:", err)
+		}
+	}
+
+	if grandfatherEmails {
+		if err := config.DB.Exec(`UPDATE users SET email_verified = true, email_verified_at = created_at`).Error; err != nil {
+			log.Fatal("Backfilling email verification failed:", err)
+		}
+	}
+
+	if backfillTotals {
+		err = config.DB.Exec(`UPDATE orders SET total = (
+			SELECT COALESCE(SUM(price * quantity), 0) FROM order_items WHERE order_items.order_id = orders.id
+		)`).Error
+		if err != nil {
+			log.Fatal("Backfilling order totals failed:", err)
+		}
+	}
+
+	// Order items placed before product snapshots existed take them from the current product
+	err = config.DB.Exec(`UPDATE order_items SET
+		product_name = products.name,
+		product_sku = products.sku,
+		product_description = products.description,
+		product_attributes = products.attributes
+	FROM products WHERE products.id = order_items.product_id AND order_items.product_name = ''`).Error
+	if err != nil {
+		log.Fatal("Backfilling order item snapshots failed:", err)
+	}
+
+	// Imports run by a previous instance of the API can't finish anymore
+	if err := importer.FailInterrupted(config.DB); err != nil {
+		log.Fatal("Failing interrupted imports failed:", err)
+	}
+
+	if err := storage.Init(); err != nil {
+		log.Fatal("Setting up storage failed:", err)
+	}
+
+	if err := mailer.Init(); err != nil {
+		log.Fatal("Setting up mailer failed:", err)
+	}
+
+	if err := jwtkeys.Init(); err != nil {
+		log.Fatal("Loading JWT keys failed:", err)
+	}
+	// Email verification links are signed with a secret even when tokens use key pairs
+	if os.Getenv("EMAIL_VERIFICATION_SECRET") == "" && os.Getenv("JWT_SECRET") == "" {
+		log.Fatal("EMAIL_VERIFICATION_SECRET or JWT_SECRET must be set")
+	}
+
+	if err := loginguard.Init(config.DB); err != nil {
+		log.Fatal("Setting up login guard failed:", err)
+	}
+
+	// Apply scheduled price changes and sales as they come due
+	pricing.StartScheduler(config.DB)
+
 	r := gin.Default()
 
+	// Files of the local storage backend are served by the API itself
+	if local, ok := storage.Default.(*storage.Local); ok {
+		r.Static(storage.LocalServePath, local.Dir)
+	}
+
 	// Setup routes
 	routes.SetupRoutes(r)
 
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AdminGetOrders godoc
+// @Summary      List orders across all users
+// @Description  Returns a paginated list of all orders, optionally filtered by status, user, creation date and total (admin only)
+// @Tags         admin-orders
+// @Security     BearerAuth
+// @Produce      json
+// @Param        status     query  string  false  "Order status (Pending|Shipped|Completed|Cancelled)"
+// @Param        user_id    query  int     false  "Only orders placed by this user"
+// @Param        from       query  string  false  "Created on or after (YYYY-MM-DD or RFC3339)"
+// @Param        to         query  string  false  "Created on or before (YYYY-MM-DD or RFC3339)"
+// @Param        min_total  query  number  false  "Minimum order total"
+// @Param        max_total  query  number  false  "Maximum order total"
+// @Param        page       query  int     false  "Page number (default 1)"
+// @Param        page_size  query  int     false  "Page size (default 20, max 100)"
+// @Success      200  {object} AdminGetOrdersResponse
+// @Failure      400,401,403,500 {object} ErrorResponse
+// @Router       /api/admin/orders [get]
+func AdminGetOrders(c *gin.Context) {
+	query := config.DB.Model(&models.Order{})
+
+	if status := c.Query("status"); status != "" {
+		if !isValidOrderStatus(status) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid order status"})
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user_id"})
+			return
+		}
+		query = query.Where("user_id = ?", id)
+	}
+
+	if from := c.Query("from"); from != "" {
+		t, _, err := parseDateParam(from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid from date"})
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+
+	if to := c.Query("to"); to != "" {
+		t, dateOnly, err := parseDateParam(to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid to date"})
+			return
+		}
+		// A bare date includes the whole day
+		if dateOnly {
+			query = query.Where("created_at < ?", t.AddDate(0, 0, 1))
+		} else {
+			query = query.Where("created_at <= ?", t)
+		}
+	}
+
+	if minTotal := c.Query("min_total"); minTotal != "" {
+		v, err := strconv.ParseFloat(minTotal, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid min_total"})
+			return
+		}
+		query = query.Where("total >= ?", v)
+	}
+
+	if maxTotal := c.Query("max_total"); maxTotal != "" {
+		v, err := strconv.ParseFloat(maxTotal, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid max_total"})
+			return
+		}
+		query = query.Where("total <= ?", v)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch orders"})
+		return
+	}
+
+	page, pageSize := paginationParams(c)
+
+	var orders []models.Order
+	if err := query.Preload("Products").
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&orders).Error; err != nil {
+
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch orders"})
+		return
+	}
+
+	orderPayloads := make([]OrderPayload, 0, len(orders))
+	for _, o := range orders {
+		orderPayloads = append(orderPayloads, toOrderPayload(o))
+	}
+
+	c.JSON(http.StatusOK, AdminGetOrdersResponse{
+		Data: orderPayloads,
+		Pagination: PaginationPayload{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: count,
+		},
+	})
+}
+
+// AdminGetOrderByID godoc
+// @Summary      Get any order by its ID
+// @Description  Returns a single order with its customer and status history (admin only).
+// @Description  The ETag header carries the order version for use with If-Match.
+// @Tags         admin-orders
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Order ID"
+// @Success      200  {object}  AdminOrderDetailResponse
+// @Failure      400,401,403,404 {object} ErrorResponse
+// @Router       /api/admin/orders/{id} [get]
+func AdminGetOrderByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Preload("Products").
+		Preload("User", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("User.Roles").
+		Preload("History", orderHistoryByDate).
+		First(&order, id).Error; err != nil {
+
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Order not found"})
+		return
+	}
+
+	setETag(c, order.Version)
+	c.JSON(http.StatusOK, AdminOrderDetailResponse{
+		Data: AdminOrderDetailPayload{
+			OrderDetailPayload: toOrderDetailPayload(order),
+			Customer:           toUserPayload(order.User),
+		},
+	})
+}
+
+// isValidOrderStatus reports whether s is one of the known order statuses
+func isValidOrderStatus(s string) bool {
+	switch models.OrderStatus(s) {
+	case models.Pending, models.Shipped, models.Completed, models.Cancelled:
+		return true
+	}
+	return false
+}
+
+// parseDateParam accepts either a bare date (YYYY-MM-DD) or an RFC3339 timestamp.
+// dateOnly is true when a bare date was given.
+func parseDateParam(s string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse("2006-01-02", s); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, s)
+	return t, false, err
+}
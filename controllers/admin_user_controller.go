@@ -0,0 +1,539 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/loginguard"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/rbac"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Reasons recorded on sessions revoked by admins
+const (
+	revokedAccountDisabled = "account disabled"
+	revokedAccountDeleted  = "account deleted"
+	revokedForcedReset     = "forced password reset"
+)
+
+// userStats are the order figures shown on an admin's view of a user
+type userStats struct {
+	UserID        uint
+	OrderCount    int64
+	LifetimeSpend float64
+	LastOrderAt   *time.Time
+}
+
+// AdminGetUsers godoc
+// @Summary      List users
+// @Description  Returns a paginated list of user accounts, optionally searched by email and filtered by role or status (requires customers:read)
+// @Tags         admin-users
+// @Security     BearerAuth
+// @Produce      json
+// @Param        q          query  string  false  "Part of the email address"
+// @Param        role       query  string  false  "Only users holding this role; 'none' for customers"
+// @Param        disabled   query  bool    false  "Only disabled (true) or active (false) accounts"
+// @Param        page       query  int     false  "Page number (default 1)"
+// @Param        page_size  query  int     false  "Page size (default 20, max 100)"
+// @Success      200  {object}  AdminGetUsersResponse
+// @Failure      400,401,403,500 {object} ErrorResponse
+// @Router       /api/admin/users [get]
+func AdminGetUsers(c *gin.Context) {
+	query := config.DB.Model(&models.User{})
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("email ILIKE ?", "%"+escapeLike(q)+"%")
+	}
+
+	switch role := c.Query("role"); role {
+	case "":
+	case "none":
+		query = query.Where("id NOT IN (SELECT user_id FROM user_roles)")
+	default:
+		query = query.Where("id IN (SELECT user_roles.user_id FROM user_roles JOIN roles ON roles.id = user_roles.role_id WHERE roles.name = ?)", role)
+	}
+
+	if disabled := c.Query("disabled"); disabled != "" {
+		v, err := strconv.ParseBool(disabled)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid disabled filter"})
+			return
+		}
+		if v {
+			query = query.Where("disabled_at IS NOT NULL")
+		} else {
+			query = query.Where("disabled_at IS NULL")
+		}
+	}
+
+	page, pageSize := paginationParams(c)
+
+	var count int64
+	var users []models.User
+	if err := query.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch users"})
+		return
+	}
+	if err := query.Preload("Roles").Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch users"})
+		return
+	}
+
+	payloads := make([]AdminUserPayload, 0, len(users))
+	for _, u := range users {
+		payloads = append(payloads, toAdminUserPayload(u))
+	}
+
+	c.JSON(http.StatusOK, AdminGetUsersResponse{
+		Data: payloads,
+		Pagination: PaginationPayload{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: count,
+		},
+	})
+}
+
+// AdminGetUserByID godoc
+// @Summary      Get a user
+// @Description  Returns a user account with its order count, lifetime spend and last order date (requires customers:read).
+// @Description  Cancelled orders don't count towards the spend.
+// @Tags         admin-users
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "User ID"
+// @Success      200  {object}  AdminUserDetailResponse
+// @Failure      401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/users/{id} [get]
+func AdminGetUserByID(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	var stats userStats
+	err := config.DB.Model(&models.Order{}).
+		Select("COUNT(*) AS order_count, COALESCE(SUM(total) FILTER (WHERE status <> ?), 0) AS lifetime_spend, MAX(created_at) AS last_order_at", models.Cancelled).
+		Where("user_id = ?", user.ID).
+		Scan(&stats).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch user"})
+		return
+	}
+
+	payload := AdminUserDetailPayload{
+		AdminUserPayload: toAdminUserPayload(user),
+		OrderCount:       stats.OrderCount,
+		LifetimeSpend:    stats.LifetimeSpend,
+		LastOrderAt:      stats.LastOrderAt,
+	}
+	if counter, err := loginguard.Default.Status(c.Request.Context(), user.Email); err == nil {
+		payload.FailedLogins = counter.Failures
+		if time.Now().Before(counter.LockedUntil) {
+			payload.LoginLockedUntil = &counter.LockedUntil
+		}
+	}
+
+	c.JSON(http.StatusOK, AdminUserDetailResponse{Data: payload})
+}
+
+// DisableUser godoc
+// @Summary      Disable a user account
+// @Description  Blocks an account from logging in and logs it out everywhere (requires customers:manage, and staff:manage for staff accounts)
+// @Tags         admin-users
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int               true   "User ID"
+// @Param        body  body  DisableUserInput  false  "Reason"
+// @Success      200  {object}  AdminUserResponse
+// @Failure      400,401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/admin/users/{id}/disable [post]
+func DisableUser(c *gin.Context) {
+	user, ok := findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	var input DisableUserInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	now := time.Now()
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"disabled_at":     now,
+			"disabled_reason": input.Reason,
+		}).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID, revokedAccountDisabled)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to disable user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AdminUserResponse{Data: toAdminUserPayload(user)})
+}
+
+// EnableUser godoc
+// @Summary      Re-enable a user account
+// @Description  Lets a disabled account log in again (requires customers:manage, and staff:manage for staff accounts)
+// @Tags         admin-users
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "User ID"
+// @Success      200  {object}  AdminUserResponse
+// @Failure      401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/users/{id}/enable [post]
+func EnableUser(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+	if !mayManage(c, user) {
+		return
+	}
+
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"disabled_at":     nil,
+		"disabled_reason": "",
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to enable user"})
+		return
+	}
+	user.DisabledAt = nil
+
+	c.JSON(http.StatusOK, AdminUserResponse{Data: toAdminUserPayload(user)})
+}
+
+// ForcePasswordReset godoc
+// @Summary      Force a password reset
+// @Description  Logs the user out everywhere and emails them a password reset link; they can't log in until they've used it (requires customers:manage, and staff:manage for staff accounts)
+// @Tags         admin-users
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "User ID"
+// @Success      200  {object}  AdminUserResponse
+// @Failure      401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/admin/users/{id}/force-password-reset [post]
+func ForcePasswordReset(c *gin.Context) {
+	user, ok := findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("must_reset_password", true).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID, revokedForcedReset)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to force password reset"})
+		return
+	}
+
+	msg := "An administrator has asked you to choose a new password for your account.\n\n" +
+		"Open this link within %s to set it:\n\n%s\n\n" +
+		"You can't log in until you've set a new password. If the link expires, request a new one from the login page.\n"
+	if err := sendPasswordResetEmail(c.Request.Context(), user, msg); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Password reset is required, but the email could not be sent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AdminUserResponse{Data: toAdminUserPayload(user)})
+}
+
+// UnlockUser godoc
+// @Summary      Unlock a user's login
+// @Description  Clears the failed login count of an account, lifting a lockout (requires customers:manage, and staff:manage for staff accounts)
+// @Tags         admin-users
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "User ID"
+// @Success      200  {object}  MessageResponse
+// @Failure      401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/users/{id}/unlock [post]
+func UnlockUser(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+	if !mayManage(c, user) {
+		return
+	}
+
+	if err := loginguard.Default.Unlock(c.Request.Context(), user.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "User unlocked"})
+}
+
+// AdminGetLoginFailures godoc
+// @Summary      List failed logins
+// @Description  Returns the audit log of failed login attempts, newest first (requires customers:read)
+// @Tags         admin-users
+// @Security     BearerAuth
+// @Produce      json
+// @Param        email      query  string  false  "Email as entered"
+// @Param        user_id    query  int     false  "Account the email belongs to"
+// @Param        ip         query  string  false  "Client IP"
+// @Param        page       query  int     false  "Page number"
+// @Param        page_size  query  int     false  "Items per page"
+// @Success      200  {object}  GetLoginFailuresResponse
+// @Failure      400,401,403,500 {object} ErrorResponse
+// @Router       /api/admin/login-failures [get]
+func AdminGetLoginFailures(c *gin.Context) {
+	query := config.DB.Model(&models.LoginFailure{})
+
+	if email := strings.TrimSpace(c.Query("email")); email != "" {
+		query = query.Where("LOWER(email) = LOWER(?)", email)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user_id filter"})
+			return
+		}
+		query = query.Where("user_id = ?", id)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+
+	page, pageSize := paginationParams(c)
+
+	var count int64
+	var failures []models.LoginFailure
+	if err := query.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch failed logins"})
+		return
+	}
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&failures).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch failed logins"})
+		return
+	}
+
+	data := make([]LoginFailurePayload, 0, len(failures))
+	for _, f := range failures {
+		data = append(data, LoginFailurePayload{
+			ID:        f.ID,
+			Email:     f.Email,
+			UserID:    f.UserID,
+			IP:        f.IP,
+			UserAgent: f.UserAgent,
+			Reason:    f.Reason,
+			CreatedAt: f.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, GetLoginFailuresResponse{
+		Data:       data,
+		Pagination: PaginationPayload{Page: page, PageSize: pageSize, TotalItems: count},
+	})
+}
+
+// ResetTwoFactor godoc
+// @Summary      Reset a user's two-factor authentication
+// @Description  Turns two-factor authentication off for a user who lost their device and their recovery codes,
+// @Description  so they can log in with their password and set it up again (requires staff:manage)
+// @Tags         admin-users
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "User ID"
+// @Success      200  {object}  AdminUserResponse
+// @Failure      401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/admin/users/{id}/reset-2fa [post]
+func ResetTwoFactor(c *gin.Context) {
+	// Not findManagedUser: taking away a lost second factor doesn't lock anyone out, so the last
+	// super-admin must be able to get it reset
+	user, ok := loadManagedUser(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error { return clearTwoFactor(tx, user.ID) }); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to reset two-factor authentication"})
+		return
+	}
+
+	user.TOTPEnabledAt = nil
+	c.JSON(http.StatusOK, AdminUserResponse{Data: toAdminUserPayload(user)})
+}
+
+// DeleteUser godoc
+// @Summary      Delete a user account
+// @Description  Deletes an account: it is logged out, its email and password are wiped and it can't be used again.
+// @Description  Its orders are kept for bookkeeping (requires customers:manage, and staff:manage for staff accounts).
+// @Tags         admin-users
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "User ID"
+// @Success      200  {object}  MessageResponse
+// @Failure      401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/admin/users/{id} [delete]
+func DeleteUser(c *gin.Context) {
+	user, ok := findManagedUser(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		// Wishlists may be shared publicly, so they go with the account
+		if err := tx.Where("wishlist_id IN (?)", tx.Model(&models.Wishlist{}).Select("id").Where("user_id = ?", user.ID)).
+			Delete(&models.WishlistItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Wishlist{}).Error; err != nil {
+			return err
+		}
+		if err := revokeUserSessions(tx, user.ID, revokedAccountDeleted); err != nil {
+			return err
+		}
+		if err := clearTwoFactor(tx, user.ID); err != nil {
+			return err
+		}
+		// Free the address so it can register again, and make sure nobody can log in with the old account
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"email":         fmt.Sprintf("deleted-%d@deleted.invalid", user.ID),
+			"pending_email": "",
+			"password":      "",
+			"name":          "",
+			"phone":         "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&user).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "User deleted"})
+}
+
+// findManagedUser loads the user in the :id param for a disruptive admin action, like loadManagedUser,
+// and also protects the last super-admin. It writes the error response and returns false on failure.
+func findManagedUser(c *gin.Context) (models.User, bool) {
+	user, ok := loadManagedUser(c)
+	if !ok {
+		return models.User{}, false
+	}
+
+	if hasRole(user.Roles, rbac.SuperAdmin) {
+		if err := ensureOtherSuperAdmin(config.DB, user.ID); err != nil {
+			if errors.Is(err, errLastSuperAdmin) {
+				c.JSON(http.StatusConflict, ErrorResponse{Error: "The last active super-admin can't be locked out"})
+			} else {
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not check super-admins"})
+			}
+			return models.User{}, false
+		}
+	}
+
+	return user, true
+}
+
+// loadManagedUser loads the user in the :id param for an admin action. Admins can't act on themselves
+// and staff accounts need staff:manage. It writes the error response and returns false on failure.
+func loadManagedUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return models.User{}, false
+	}
+
+	if user.ID == c.GetUint("user_id") {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "You can't do this to your own account"})
+		return models.User{}, false
+	}
+
+	if !mayManage(c, user) {
+		return models.User{}, false
+	}
+
+	return user, true
+}
+
+// mayManage checks that the authenticated user holds staff:manage when user has any role, so customers:manage
+// alone can't be used against staff accounts. It writes the error response and returns false otherwise.
+func mayManage(c *gin.Context, user models.User) bool {
+	if len(user.Roles) == 0 {
+		return true
+	}
+
+	// The permission middleware in front of admin routes has usually read them already
+	if cached, exists := c.Get("permissions"); exists {
+		if cached.(map[string]bool)[rbac.ManageStaff] {
+			return true
+		}
+	} else {
+		permissions, err := rbac.UserPermissions(config.DB, c.GetUint("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not check permissions"})
+			return false
+		}
+		if slices.Contains(permissions, rbac.ManageStaff) {
+			return true
+		}
+	}
+
+	c.JSON(http.StatusForbidden, ErrorResponse{Error: "Missing permission " + rbac.ManageStaff + " to act on staff accounts"})
+	return false
+}
+
+// ensureOtherSuperAdmin fails with errLastSuperAdmin unless an active super-admin other than userID exists
+func ensureOtherSuperAdmin(db *gorm.DB, userID uint) error {
+	var others int64
+	err := db.Table("user_roles").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Where("roles.name = ? AND users.id <> ? AND users.disabled_at IS NULL AND users.deleted_at IS NULL", rbac.SuperAdmin, userID).
+		Count(&others).Error
+	if err != nil {
+		return err
+	}
+	if others == 0 {
+		return errLastSuperAdmin
+	}
+	return nil
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// toAdminUserPayload converts a user into the payload shown to admins
+func toAdminUserPayload(user models.User) AdminUserPayload {
+	return AdminUserPayload{
+		UserPayload:       toUserPayload(user),
+		DisabledAt:        user.DisabledAt,
+		DisabledReason:    user.DisabledReason,
+		MustResetPassword: user.MustResetPassword,
+		CreatedAt:         user.CreatedAt,
+	}
+}
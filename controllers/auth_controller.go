@@ -1,15 +1,22 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/Emibrown/E-commerce-API/config"
 	"github.com/Emibrown/E-commerce-API/models"
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// errRefreshTokenReused is returned when a refresh token is used a second time
+	errRefreshTokenReused = errors.New("refresh token reused")
+	// errResetTokenUsed is returned when a password reset token was used concurrently
+	errResetTokenUsed = errors.New("password reset token already used")
 )
 
 type RegisterInput struct {
@@ -22,15 +29,13 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
-type AdminRegisterInput struct {
-	Email       string `json:"email" binding:"required,email"`
-	Password    string `json:"password" binding:"required,min=6"`
-	AdminSecret string `json:"admin_secret" binding:"required"`
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // Register godoc
 // @Summary      Register a new user
-// @Description  Registers a new user with email and password
+// @Description  Registers a new user with email and password and sends them an email verification link
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -61,25 +66,27 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// The account works without it; the user can ask for the email again
+	sendVerificationEmail(c, user, user.Email)
+
 	c.JSON(http.StatusCreated, RegisterResponse{
 		Message: "User registered successfully",
-		User: UserPayload{
-			ID:      user.ID,
-			Email:   user.Email,
-			IsAdmin: user.IsAdmin,
-		},
+		User:    toUserPayload(user),
 	})
 }
 
 // Login godoc
 // @Summary      Login a user
-// @Description  Logs in an existing user and returns a JWT token
+// @Description  Logs in an existing user and starts a session. Returns a short-lived JWT access token and a refresh token for getting new ones.
+// @Description  Accounts with two-factor authentication get a challenge token instead (202), to finish at /api/auth/login/2fa.
+// @Description  Repeated failures slow down further attempts for the account and the client's IP, answered with 429 and Retry-After.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        body  body   LoginInput  true  "Login Input"
 // @Success      200   {object} LoginResponse
-// @Failure      400,401 {object} ErrorResponse
+// @Success      202   {object} TwoFactorChallengeResponse
+// @Failure      400,401,403,429,500 {object} ErrorResponse
 // @Router       /api/auth/login [post]
 func Login(c *gin.Context) {
 	var input LoginInput
@@ -89,78 +96,192 @@ func Login(c *gin.Context) {
 	}
 
 	var user models.User
-	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+	var userID *uint
+	found := config.DB.Where("email = ?", input.Email).First(&user).Error == nil
+	if found {
+		userID = &user.ID
+	}
+
+	if !reserveLogin(c, input.Email, userID) {
+		return
+	}
+
+	if !found {
+		auditLoginFailure(c, input.Email, nil, loginFailedUnknownEmail)
 		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid email or password"})
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		auditLoginFailure(c, input.Email, userID, loginFailedWrongPassword)
 		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid email or password"})
 		return
 	}
 
-	// Create JWT
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":  user.ID,
-		"is_admin": user.IsAdmin,
-		"exp":      time.Now().Add(72 * time.Hour).Unix(),
-	})
+	// With two-factor authentication the failures are only cleared once the code is right too
+	if user.TOTPEnabledAt == nil {
+		loginSucceeded(c, input.Email)
+	} else {
+		refundLogin(c, input.Email)
+	}
+
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Account is disabled"})
+		return
+	}
+	if user.MustResetPassword {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Password must be reset; use the link sent by email or request a new one"})
+		return
+	}
+
+	if user.TOTPEnabledAt != nil {
+		challenge, err := startTwoFactorChallenge(user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not start login"})
+			return
+		}
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
 
-	tokenString, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	tokens, err := startSession(config.DB, c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create token"})
 		return
 	}
 
-	c.JSON(http.StatusOK, LoginResponse{Token: tokenString})
+	c.JSON(http.StatusOK, tokens)
 }
 
-// RegisterAdmin godoc
-// @Summary      Register a new admin user
-// @Description  Creates a new admin user if a valid admin secret is provided
+// Refresh godoc
+// @Summary      Refresh an access token
+// @Description  Exchanges a refresh token for a new access token and a new refresh token. Each refresh token works once;
+// @Description  presenting one that was already used revokes the whole session.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
-// @Param        body  body   AdminRegisterInput  true  "Admin Register Input"
-// @Success      201   {object} RegisterResponse
-// @Failure      400,401 {object} ErrorResponse
-// @Router       /api/auth/register-admin [post]
-func RegisterAdmin(c *gin.Context) {
-	var input AdminRegisterInput
+// @Param        body  body   RefreshInput  true  "Refresh token"
+// @Success      200   {object} LoginResponse
+// @Failure      400,401,500 {object} ErrorResponse
+// @Router       /api/auth/refresh [post]
+func Refresh(c *gin.Context) {
+	var input RefreshInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Check admin secret
-	if input.AdminSecret != os.Getenv("ADMIN_SECRET") {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid admin secret"})
+	var refreshToken models.RefreshToken
+	if err := config.DB.Where("token_hash = ?", hashToken(input.RefreshToken)).First(&refreshToken).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid refresh token"})
 		return
 	}
 
-	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Unable to hash password"})
+	var session models.Session
+	if err := config.DB.First(&session, refreshToken.SessionID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid refresh token"})
 		return
 	}
 
-	user := models.User{
-		Email:    input.Email,
-		Password: string(hashedPwd),
-		IsAdmin:  true,
+	// A used token showing up again means it was copied; the thief and the owner can't be told apart,
+	// so the session is ended for both
+	if refreshToken.UsedAt != nil {
+		revokeSession(config.DB, session.ID, revokedRefreshReuse)
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Refresh token has already been used"})
+		return
 	}
 
-	if err := config.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Unable to create admin user"})
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) || now.After(refreshToken.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Session has expired"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, RegisterResponse{
-		Message: "Admin user registered successfully",
-		User: UserPayload{
-			ID:      user.ID,
-			Email:   user.Email,
-			IsAdmin: user.IsAdmin,
-		},
+	var user models.User
+	if err := config.DB.First(&user, session.UserID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid refresh token"})
+		return
+	}
+	if user.DisabledAt != nil || user.MustResetPassword {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Session has expired"})
+		return
+	}
+
+	var tokens LoginResponse
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Two requests racing with the same token are treated as reuse as well
+		result := tx.Model(&refreshToken).Where("used_at IS NULL").Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		session.ExpiresAt = now.Add(envDuration("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL))
+		session.LastUsedAt = now
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"expires_at":   session.ExpiresAt,
+			"last_used_at": session.LastUsedAt,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		tokens, err = issueTokens(tx, session, user)
+		return err
 	})
+	if err != nil {
+		if errors.Is(err, errRefreshTokenReused) {
+			revokeSession(config.DB, session.ID, revokedRefreshReuse)
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Refresh token has already been used"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout godoc
+// @Summary      Log out
+// @Description  Revokes the current session. Its access and refresh tokens stop working immediately.
+// @Tags         auth
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200   {object} LogoutResponse
+// @Failure      401,500 {object} ErrorResponse
+// @Router       /api/auth/logout [post]
+func Logout(c *gin.Context) {
+	if err := revokeSession(config.DB, c.GetUint("session_id"), revokedLogout); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, LogoutResponse{Message: "Logged out"})
+}
+
+// toUserPayload converts a user into its response payload
+func toUserPayload(user models.User) UserPayload {
+	return UserPayload{
+		ID:               user.ID,
+		Email:            user.Email,
+		PendingEmail:     user.PendingEmail,
+		Name:             user.Name,
+		Phone:            user.Phone,
+		Roles:            roleNames(user.Roles),
+		EmailVerified:    user.EmailVerified,
+		TwoFactorEnabled: user.TOTPEnabledAt != nil,
+		CustomerGroupID:  user.CustomerGroupID,
+	}
+}
+
+// roleNames lists the names of roles
+func roleNames(roles []models.Role) []string {
+	names := make([]string, 0, len(roles))
+	for _, r := range roles {
+		names = append(names, r.Name)
+	}
+	return names
 }
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// bindStrictJSON binds a JSON object body onto obj like ShouldBindJSON, but rejects
+// unknown fields and any of the given immutable fields instead of silently ignoring them
+func bindStrictJSON(c *gin.Context, obj interface{}, immutable ...string) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return errors.New("could not read request body")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return errors.New("request body must be a JSON object")
+	}
+
+	for _, name := range immutable {
+		if _, ok := fields[name]; ok {
+			return fmt.Errorf("field %q cannot be changed", name)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		// encoding/json reports these as `json: unknown field "name"`
+		if strings.HasPrefix(err.Error(), "json: unknown field") {
+			return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+		}
+		return err
+	}
+
+	return binding.Validator.ValidateStruct(obj)
+}
@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateCustomerGroup godoc
+// @Summary      Create a customer group
+// @Description  Adds a customer group that can be given its own price list (admin only)
+// @Tags         pricing
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  CustomerGroupInput  true  "Customer group"
+// @Success      201  {object}  CustomerGroupResponse
+// @Failure      400,401,403,409,500 {object} ErrorResponse
+// @Router       /api/admin/customer-groups [post]
+func CreateCustomerGroup(c *gin.Context) {
+	var input CustomerGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if customerGroupNameTaken(input.Name, 0) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "A customer group with this name already exists"})
+		return
+	}
+
+	group := models.CustomerGroup{Name: input.Name, Description: input.Description}
+	if err := config.DB.Create(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create customer group"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CustomerGroupResponse{Data: toCustomerGroupPayload(group)})
+}
+
+// GetCustomerGroups godoc
+// @Summary      List customer groups
+// @Description  Returns all customer groups (admin only)
+// @Tags         pricing
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  GetCustomerGroupsResponse
+// @Failure      401,403,500 {object} ErrorResponse
+// @Router       /api/admin/customer-groups [get]
+func GetCustomerGroups(c *gin.Context) {
+	var groups []models.CustomerGroup
+	if err := config.DB.Order("name ASC").Find(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch customer groups"})
+		return
+	}
+
+	payloads := make([]CustomerGroupPayload, 0, len(groups))
+	for _, g := range groups {
+		payloads = append(payloads, toCustomerGroupPayload(g))
+	}
+
+	c.JSON(http.StatusOK, GetCustomerGroupsResponse{Data: payloads})
+}
+
+// UpdateCustomerGroup godoc
+// @Summary      Update a customer group
+// @Description  Changes a customer group's name and description (admin only)
+// @Tags         pricing
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int                 true  "Customer group ID"
+// @Param        body  body  CustomerGroupInput  true  "Customer group"
+// @Success      200  {object}  CustomerGroupResponse
+// @Failure      400,401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/admin/customer-groups/{id} [put]
+func UpdateCustomerGroup(c *gin.Context) {
+	var group models.CustomerGroup
+	if err := config.DB.First(&group, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Customer group not found"})
+		return
+	}
+
+	var input CustomerGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if customerGroupNameTaken(input.Name, group.ID) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "A customer group with this name already exists"})
+		return
+	}
+
+	group.Name = input.Name
+	group.Description = input.Description
+	if err := config.DB.Save(&group).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update customer group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, CustomerGroupResponse{Data: toCustomerGroupPayload(group)})
+}
+
+// DeleteCustomerGroup godoc
+// @Summary      Delete a customer group
+// @Description  Deletes a customer group and its price tiers. Its members go back to regular prices (admin only).
+// @Tags         pricing
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "Customer group ID"
+// @Success      200  {object}  DeleteCustomerGroupResponse
+// @Failure      401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/customer-groups/{id} [delete]
+func DeleteCustomerGroup(c *gin.Context) {
+	var group models.CustomerGroup
+	if err := config.DB.First(&group, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Customer group not found"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("customer_group_id = ?", group.ID).Update("customer_group_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("customer_group_id = ?", group.ID).Delete(&models.ProductPriceTier{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&group).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete customer group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, DeleteCustomerGroupResponse{Message: "Customer group deleted"})
+}
+
+// SetUserCustomerGroup godoc
+// @Summary      Assign a user to a customer group
+// @Description  Puts a user in a customer group, or back on regular prices when customer_group_id is null (admin only)
+// @Tags         pricing
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int                     true  "User ID"
+// @Param        body  body  UserCustomerGroupInput  true  "Customer group"
+// @Success      200  {object}  UserResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/users/{id}/customer-group [put]
+func SetUserCustomerGroup(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	var input UserCustomerGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if input.CustomerGroupID != nil {
+		var group models.CustomerGroup
+		if err := config.DB.First(&group, *input.CustomerGroupID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Customer group not found"})
+			return
+		}
+	}
+
+	if err := config.DB.Model(&user).Update("customer_group_id", input.CustomerGroupID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update user"})
+		return
+	}
+	user.CustomerGroupID = input.CustomerGroupID
+
+	c.JSON(http.StatusOK, UserResponse{Data: toUserPayload(user)})
+}
+
+// GetProductPriceTiers godoc
+// @Summary      List a product's price tiers
+// @Description  Returns the group and quantity-break prices of a product (admin only)
+// @Tags         pricing
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "Product ID"
+// @Success      200  {object}  PriceTiersResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/price-tiers [get]
+func GetProductPriceTiers(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Unscoped().First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	var tiers []models.ProductPriceTier
+	if err := config.DB.Where("product_id = ?", product.ID).
+		Order("customer_group_id ASC NULLS FIRST, min_quantity ASC").
+		Find(&tiers).Error; err != nil {
+
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch price tiers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PriceTiersResponse{Data: toPriceTierPayloads(tiers)})
+}
+
+// CreateProductPriceTier godoc
+// @Summary      Add a price tier to a product
+// @Description  Sets the unit price a customer group (or everyone, when customer_group_id is omitted) pays from a minimum quantity.
+// @Description  Setting a tier that already exists for the same group and minimum quantity replaces its price (admin only).
+// @Tags         pricing
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int             true  "Product ID"
+// @Param        body  body  PriceTierInput  true  "Price tier"
+// @Success      201  {object}  PriceTierResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/price-tiers [post]
+func CreateProductPriceTier(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	var input PriceTierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if input.MinQuantity == 0 {
+		input.MinQuantity = 1
+	}
+	if input.CustomerGroupID == nil && input.MinQuantity == 1 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A tier for everyone needs a min_quantity above 1; change the product price instead"})
+		return
+	}
+
+	if input.CustomerGroupID != nil {
+		var group models.CustomerGroup
+		if err := config.DB.First(&group, *input.CustomerGroupID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Customer group not found"})
+			return
+		}
+	}
+
+	// NULL never equals NULL, so look the existing tier up explicitly rather than relying on a unique index
+	query := config.DB.Where("product_id = ? AND min_quantity = ?", product.ID, input.MinQuantity)
+	if input.CustomerGroupID != nil {
+		query = query.Where("customer_group_id = ?", *input.CustomerGroupID)
+	} else {
+		query = query.Where("customer_group_id IS NULL")
+	}
+
+	var tier models.ProductPriceTier
+	if err := query.Limit(1).Find(&tier).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not save price tier"})
+		return
+	}
+
+	tier.ProductID = product.ID
+	tier.CustomerGroupID = input.CustomerGroupID
+	tier.MinQuantity = input.MinQuantity
+	tier.Price = input.Price
+	if err := config.DB.Save(&tier).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not save price tier"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, PriceTierResponse{Data: toPriceTierPayload(tier)})
+}
+
+// DeleteProductPriceTier godoc
+// @Summary      Remove a price tier from a product
+// @Description  Deletes one of a product's price tiers (admin only)
+// @Tags         pricing
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id       path  int  true  "Product ID"
+// @Param        tier_id  path  int  true  "Price tier ID"
+// @Success      200  {object}  DeletePriceTierResponse
+// @Failure      401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/price-tiers/{tier_id} [delete]
+func DeleteProductPriceTier(c *gin.Context) {
+	result := config.DB.Where("id = ? AND product_id = ?", c.Param("tier_id"), c.Param("id")).Delete(&models.ProductPriceTier{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete price tier"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Price tier not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, DeletePriceTierResponse{Message: "Price tier deleted"})
+}
+
+// customerGroupNameTaken reports whether another customer group (other than excludeID) already uses name
+func customerGroupNameTaken(name string, excludeID uint) bool {
+	var count int64
+	config.DB.Model(&models.CustomerGroup{}).Where("name = ? AND id <> ?", name, excludeID).Count(&count)
+	return count > 0
+}
+
+// toCustomerGroupPayload converts a customer group into its response payload
+func toCustomerGroupPayload(group models.CustomerGroup) CustomerGroupPayload {
+	return CustomerGroupPayload{
+		ID:          group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		CreatedAt:   group.CreatedAt,
+		UpdatedAt:   group.UpdatedAt,
+	}
+}
+
+// toPriceTierPayload converts a price tier into its response payload
+func toPriceTierPayload(tier models.ProductPriceTier) PriceTierPayload {
+	return PriceTierPayload{
+		ID:              tier.ID,
+		ProductID:       tier.ProductID,
+		CustomerGroupID: tier.CustomerGroupID,
+		MinQuantity:     tier.MinQuantity,
+		Price:           tier.Price,
+	}
+}
+
+// toPriceTierPayloads converts price tiers into response payloads
+func toPriceTierPayloads(tiers []models.ProductPriceTier) []PriceTierPayload {
+	payloads := make([]PriceTierPayload, 0, len(tiers))
+	for _, t := range tiers {
+		payloads = append(payloads, toPriceTierPayload(t))
+	}
+	return payloads
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/mailer"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultVerificationTTL            = 48 * time.Hour
+	defaultVerificationResendInterval = 5 * time.Minute
+)
+
+var errInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail godoc
+// @Summary      Verify an email address
+// @Description  Marks the account's email address as verified using the token from the verification email.
+// @Description  For a link sent after an email change, this switches the account to the new address.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   VerifyEmailInput  true  "Verification token"
+// @Success      200   {object} MessageResponse
+// @Failure      400,409,500 {object} ErrorResponse
+// @Router       /api/auth/verify-email [post]
+func VerifyEmail(c *gin.Context) {
+	var input VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	userID, email, err := parseVerificationToken(input.Token, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid or expired verification token"})
+		return
+	}
+
+	// The token names the address it was sent to, so links to an old address stop working after a change
+	var user models.User
+	if err := config.DB.Where("id = ? AND (email = ? OR pending_email = ?)", userID, email, email).First(&user).Error; err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid or expired verification token"})
+		return
+	}
+
+	if user.Email != email {
+		confirmEmailChange(c, user)
+		return
+	}
+
+	if !user.EmailVerified {
+		now := time.Now()
+		if err := config.DB.Model(&user).Updates(map[string]interface{}{
+			"email_verified":    true,
+			"email_verified_at": now,
+		}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not verify email"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Email address verified"})
+}
+
+// ResendVerificationEmail godoc
+// @Summary      Resend the verification email
+// @Description  Sends a new verification link to the authenticated user's address, or to the new address of a pending email change.
+// @Description  Resending is throttled.
+// @Tags         auth
+// @Security     BearerAuth
+// @Produce      json
+// @Success      202   {object} MessageResponse
+// @Failure      400,401,429,500 {object} ErrorResponse
+// @Router       /api/auth/verify-email/resend [post]
+func ResendVerificationEmail(c *gin.Context) {
+	var user models.User
+	if err := config.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	address := user.Email
+	if user.PendingEmail != "" {
+		address = user.PendingEmail
+	} else if user.EmailVerified {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Email address is already verified"})
+		return
+	}
+
+	if verificationThrottled(c, user) {
+		return
+	}
+
+	if err := sendVerificationEmail(c, user, address); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, MessageResponse{Message: "Verification email sent"})
+}
+
+// verificationThrottled answers with a 429 and returns true if user was sent a verification email
+// less than EMAIL_VERIFICATION_RESEND_INTERVAL ago
+func verificationThrottled(c *gin.Context, user models.User) bool {
+	if user.VerificationSentAt == nil {
+		return false
+	}
+	interval := envDuration("EMAIL_VERIFICATION_RESEND_INTERVAL", defaultVerificationResendInterval)
+	wait := time.Until(user.VerificationSentAt.Add(interval))
+	if wait <= 0 {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "A verification email was sent recently, please wait before asking again"})
+	return true
+}
+
+// sendVerificationEmail mails a signed verification link for address, which is either the user's email or
+// the pending new one, and remembers when, for throttling
+func sendVerificationEmail(c *gin.Context, user models.User, address string) error {
+	ttl := envDuration("EMAIL_VERIFICATION_TOKEN_TTL", defaultVerificationTTL)
+	token := signVerificationToken(user.ID, address, time.Now().Add(ttl))
+	link := frontendLink("EMAIL_VERIFICATION_URL", "/verify-email", token)
+
+	msg := mailer.Message{
+		To:      address,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Welcome! Please confirm that this is your email address by opening this link within %s:\n\n%s\n\n"+
+			"If you didn't create an account, you can ignore this email.\n", ttl, link),
+	}
+	if address != user.Email {
+		msg.Body = fmt.Sprintf("Please confirm that you want to use this address for your account by opening this link within %s:\n\n%s\n\n"+
+			"Until then you keep logging in with your current address. If you didn't ask for this, you can ignore this email.\n", ttl, link)
+	}
+	if err := mailer.Default.Send(c.Request.Context(), msg); err != nil {
+		log.Printf("Sending verification email to user %d failed: %v", user.ID, err)
+		return err
+	}
+
+	return config.DB.Model(&models.User{}).Where("id = ?", user.ID).Update("verification_sent_at", time.Now()).Error
+}
+
+// confirmEmailChange switches user to their verified pending address and tells the old address about it
+func confirmEmailChange(c *gin.Context, user models.User) {
+	oldEmail := user.Email
+
+	var taken int64
+	config.DB.Unscoped().Model(&models.User{}).Where("email = ? AND id <> ?", user.PendingEmail, user.ID).Count(&taken)
+	if taken > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "This email address is already used by another account"})
+		return
+	}
+
+	now := time.Now()
+	if err := config.DB.Model(&user).Updates(map[string]interface{}{
+		"email":             user.PendingEmail,
+		"pending_email":     "",
+		"email_verified":    true,
+		"email_verified_at": now,
+	}).Error; err != nil {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Could not change email address"})
+		return
+	}
+
+	msg := mailer.Message{
+		To:      oldEmail,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("The email address of your account has been changed to %s.\n\n"+
+			"If you didn't do this, please contact us right away.\n", user.Email),
+	}
+	if err := mailer.Default.Send(c.Request.Context(), msg); err != nil {
+		log.Printf("Sending email change notice to user %d failed: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Email address changed"})
+}
+
+// signVerificationToken creates a stateless token proving that whoever holds it received mail at email.
+// It has the form base64(userID:email:expiry).base64(HMAC-SHA256).
+func signVerificationToken(userID uint, email string, expires time.Time) string {
+	payload := fmt.Sprintf("%d:%s:%d", userID, email, expires.Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(verificationMAC(payload))
+}
+
+// parseVerificationToken checks a token's signature and expiry and returns the user and address it was issued for
+func parseVerificationToken(token string, now time.Time) (uint, string, error) {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, "", errInvalidVerificationToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return 0, "", errInvalidVerificationToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, verificationMAC(string(payload))) {
+		return 0, "", errInvalidVerificationToken
+	}
+
+	// Emails can't contain ':' unquoted, but split from both ends to be safe
+	first := strings.Index(string(payload), ":")
+	last := strings.LastIndex(string(payload), ":")
+	if first < 0 || first == last {
+		return 0, "", errInvalidVerificationToken
+	}
+	userID, err := strconv.ParseUint(string(payload[:first]), 10, 64)
+	if err != nil {
+		return 0, "", errInvalidVerificationToken
+	}
+	expires, err := strconv.ParseInt(string(payload[last+1:]), 10, 64)
+	if err != nil || now.Unix() > expires {
+		return 0, "", errInvalidVerificationToken
+	}
+
+	return uint(userID), string(payload[first+1 : last]), nil
+}
+
+// verificationMAC signs payload with EMAIL_VERIFICATION_SECRET, falling back to JWT_SECRET
+func verificationMAC(payload string) []byte {
+	secret := os.Getenv("EMAIL_VERIFICATION_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("email-verification:" + payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errStaleVersion is returned when a row changed between reading and writing it
+var errStaleVersion = errors.New("resource was modified concurrently")
+
+// versionETag formats a row version as an ETag header value
+func versionETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// setETag sets the ETag header for a resource at the given version
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", versionETag(version))
+}
+
+// ifMatchSatisfied reports whether the request's If-Match header (if any) matches the current version
+func ifMatchSatisfied(c *gin.Context, version uint) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+
+	current := versionETag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/exporter"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many products are loaded from the database at a time while exporting
+const exportBatchSize = 500
+
+// ExportProducts godoc
+// @Summary      Export the product catalog
+// @Description  Streams products as CSV, JSON Lines or XLSX (admin only). Filters match the product listing.
+// @Tags         products
+// @Security     BearerAuth
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param        format    query  string  false  "csv (default), jsonl or xlsx"
+// @Param        columns   query  string  false  "Comma-separated columns: id, sku, gtin, name, description, price, attributes, version, created_at, updated_at, archived_at"
+// @Param        archived  query  string  false  "Archived products: 'true' exports only archived ones, 'all' includes them"
+// @Success      200  {file}    file
+// @Failure      400,401,403 {object} ErrorResponse
+// @Router       /api/admin/products/export [get]
+func ExportProducts(c *gin.Context) {
+	format := exporter.CSV
+	if f := c.Query("format"); f != "" {
+		var err error
+		if format, err = exporter.ParseFormat(f); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	columns, err := exporter.ParseColumns(c.Query("columns"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	scope, err := productListScope(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("products-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	writer, err := exporter.NewWriter(c.Writer, format, columns)
+	if err != nil {
+		log.Printf("Product export failed: %v", err)
+		abortDownload(c)
+		return
+	}
+
+	// Once streaming has started the status can no longer change, so failures cut the connection instead
+	var products []models.Product
+	result := config.DB.Scopes(scope).FindInBatches(&products, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, p := range products {
+			if err := writer.Write(p); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if result.Error != nil {
+		log.Printf("Product export failed: %v", result.Error)
+		abortDownload(c)
+		return
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Printf("Product export failed: %v", err)
+		abortDownload(c)
+	}
+}
+
+// abortDownload closes the connection without finishing the response, so a download that failed halfway
+// shows up as failed in the client rather than as a complete but truncated file
+func abortDownload(c *gin.Context) {
+	if conn, _, err := c.Writer.Hijack(); err == nil {
+		conn.Close()
+		return
+	}
+	// Connections that can't be hijacked (HTTP/2) are reset by net/http instead
+	panic(http.ErrAbortHandler)
+}
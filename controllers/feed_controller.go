@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/feed"
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductFeed godoc
+// @Summary      Google Merchant product feed
+// @Description  Public RSS 2.0 feed of all published (non-archived) products in Google Merchant format. Product changes show up within 30 seconds.
+// @Tags         feeds
+// @Produce      xml
+// @Success      200  {string}  string  "RSS feed"
+// @Failure      500  {object}  ErrorResponse
+// @Router       /feeds/google-merchant.xml [get]
+func GetProductFeed(c *gin.Context) {
+	data, generatedAt, err := feed.Get(config.DB)
+	if err != nil {
+		log.Printf("Generating product feed failed: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not generate feed"})
+		return
+	}
+
+	c.Header("Last-Modified", generatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", data)
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/importer"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileSize limits uploads to the import endpoint
+const maxImportFileSize = 50 << 20
+
+// ImportProducts godoc
+// @Summary      Bulk import products
+// @Description  Upserts products by SKU from a CSV or JSON Lines file (admin only). The import runs in the background; poll the returned job for progress and per-row errors.
+// @Description  CSV files need a header row with the columns sku, name, price and optionally gtin, description and attributes (a JSON object).
+// @Tags         products
+// @Security     BearerAuth
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file     formData  file    true   "CSV or JSON Lines file"
+// @Param        format   query     string  false  "csv or jsonl (defaults to the file extension)"
+// @Param        dry_run  query     bool    false  "Validate and match rows without writing anything"
+// @Success      202  {object}  ImportJobResponse
+// @Failure      400,401,403,500 {object} ErrorResponse
+// @Router       /api/admin/product-imports [post]
+func ImportProducts(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A file is required"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "File is too large"})
+		return
+	}
+
+	var format importer.Format
+	if f := c.Query("format"); f != "" {
+		if format, err = importer.ParseFormat(f); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	} else {
+		var ok bool
+		if format, ok = importer.FormatFromFilename(fileHeader.Filename); !ok {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Could not tell the file format; pass format=csv or format=jsonl"})
+			return
+		}
+	}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Could not read file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Could not read file"})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	job := models.ImportJob{
+		Filename:  fileHeader.Filename,
+		Format:    string(format),
+		DryRun:    dryRun,
+		Status:    models.ImportQueued,
+		CreatedBy: &userID,
+	}
+	if err := config.DB.Create(&job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create import job"})
+		return
+	}
+
+	// The goroutine works on its own copy so the response below doesn't race with it
+	running := job
+	go func() {
+		if err := importer.Run(config.DB, &running, data); err != nil {
+			log.Printf("Import job %d failed: %v", running.ID, err)
+		}
+	}()
+
+	c.Header("Location", "/api/admin/product-imports/"+strconv.Itoa(int(job.ID)))
+	c.JSON(http.StatusAccepted, ImportJobResponse{Data: toImportJobPayload(job)})
+}
+
+// GetImportJob godoc
+// @Summary      Get a product import job
+// @Description  Returns the progress of a bulk product import and the errors of rows that failed (admin only)
+// @Tags         products
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Import job ID"
+// @Success      200  {object}  ImportJobResponse
+// @Failure      400,401,403,404 {object} ErrorResponse
+// @Router       /api/admin/product-imports/{id} [get]
+func GetImportJob(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var job models.ImportJob
+	if err := config.DB.First(&job, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Import job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ImportJobResponse{Data: toImportJobPayload(job)})
+}
+
+// toImportJobPayload converts an import job into its response payload
+func toImportJobPayload(job models.ImportJob) ImportJobPayload {
+	errs := make([]ImportRowErrorPayload, 0, len(job.Errors))
+	for _, e := range job.Errors {
+		errs = append(errs, ImportRowErrorPayload{Row: e.Row, SKU: e.SKU, Error: e.Error})
+	}
+
+	return ImportJobPayload{
+		ID:            job.ID,
+		Filename:      job.Filename,
+		Format:        job.Format,
+		DryRun:        job.DryRun,
+		Status:        string(job.Status),
+		TotalRows:     job.TotalRows,
+		ProcessedRows: job.ProcessedRows,
+		CreatedCount:  job.CreatedCount,
+		UpdatedCount:  job.UpdatedCount,
+		FailedCount:   job.FailedCount,
+		Errors:        errs,
+		Message:       job.Message,
+		StartedAt:     job.StartedAt,
+		FinishedAt:    job.FinishedAt,
+		CreatedAt:     job.CreatedAt,
+	}
+}
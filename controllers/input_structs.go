@@ -1,9 +1,116 @@
 package controllers
 
+import "time"
+
 // ------------------ Product input ------------------ //
 
 type CreateProductInput struct {
-	Name        string  `json:"name" binding:"required"`
-	Description string  `json:"description"`
-	Price       float64 `json:"price" binding:"required"`
+	SKU         string            `json:"sku"`
+	GTIN        string            `json:"gtin" binding:"omitempty,numeric,min=8,max=14"`
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	Attributes  map[string]string `json:"attributes"`
+	Price       float64           `json:"price" binding:"required,gt=0"`
+}
+
+// UpdateProductInput only changes the fields that are present in the request
+type UpdateProductInput struct {
+	SKU         *string            `json:"sku"`
+	GTIN        *string            `json:"gtin" binding:"omitempty,numeric,min=8,max=14"`
+	Name        *string            `json:"name" binding:"omitempty,min=1"`
+	Description *string            `json:"description"`
+	Attributes  *map[string]string `json:"attributes"`
+	Price       *float64           `json:"price" binding:"omitempty,gt=0"`
+}
+
+// productImmutableFields are product fields a client may see but never set
+var productImmutableFields = []string{"id", "version", "images", "created_at", "updated_at", "archived_at"}
+
+// ------------------ Staff input ------------------ //
+
+type RoleInput struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// UserRolesInput lists role names; an empty list removes every role
+type UserRolesInput struct {
+	Roles []string `json:"roles"`
+}
+
+type StaffInvitationInput struct {
+	Email string   `json:"email" binding:"required,email"`
+	Roles []string `json:"roles" binding:"required,min=1"`
+}
+
+type AcceptInvitationInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// ------------------ Admin user input ------------------ //
+
+type DisableUserInput struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// ------------------ Pricing input ------------------ //
+
+type CustomerGroupInput struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description"`
+}
+
+type UserCustomerGroupInput struct {
+	CustomerGroupID *uint `json:"customer_group_id"` // null removes the user from their group
+}
+
+type PriceTierInput struct {
+	CustomerGroupID *uint   `json:"customer_group_id"`                      // omit to apply to every customer
+	MinQuantity     int     `json:"min_quantity" binding:"omitempty,min=1"` // defaults to 1
+	Price           float64 `json:"price" binding:"required,gt=0"`
+}
+
+// SchedulePriceInput schedules a regular price change or a sale
+type SchedulePriceInput struct {
+	Kind     string     `json:"kind" binding:"required,oneof=regular sale"`
+	Price    float64    `json:"price" binding:"required,gt=0"`
+	StartsAt time.Time  `json:"starts_at" binding:"required"`
+	EndsAt   *time.Time `json:"ends_at"` // sales only; a sale without one runs until cancelled
+}
+
+// ------------------ Review input ------------------ //
+
+type CreateReviewInput struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Title  string `json:"title" binding:"max=200"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+// ------------------ Wishlist input ------------------ //
+
+type WishlistInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type WishlistItemInput struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	Quantity  int  `json:"quantity" binding:"omitempty,min=1"` // defaults to 1
+}
+
+// OrderWishlistInput selects the wishlist items to order; an empty list orders them all
+type OrderWishlistInput struct {
+	ItemIDs []uint `json:"item_ids"`
+}
+
+// ------------------ Product image input ------------------ //
+
+type UpdateProductImageInput struct {
+	AltText  *string `json:"alt_text"`
+	Position *int    `json:"position"`
+}
+
+type ReorderProductImagesInput struct {
+	ImageIDs []uint `json:"image_ids" binding:"required"`
 }
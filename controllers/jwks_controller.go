@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Emibrown/E-commerce-API/jwtkeys"
+	"github.com/gin-gonic/gin"
+)
+
+// GetJWKS godoc
+// @Summary      Get the token signing keys
+// @Description  Returns the public keys access tokens are signed with as a JSON Web Key Set, so other services can verify
+// @Description  tokens without a shared secret. Tokens name their key in the "kid" header. Empty while tokens are signed with JWT_SECRET.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  jwtkeys.JWKSet
+// @Router       /.well-known/jwks.json [get]
+func GetJWKS(c *gin.Context) {
+	// Keys only change on restart; a short cache keeps rotations visible soon enough
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwtkeys.Default.JWKS())
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/loginguard"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Reasons recorded on failed logins
+const (
+	loginFailedUnknownEmail  = "unknown email"
+	loginFailedWrongPassword = "wrong password"
+	loginFailedTwoFactor     = "wrong two-factor code"
+	loginFailedCurrentPass   = "wrong current password"
+	loginFailedLocked        = "locked"
+)
+
+// reserveLogin counts a login for email from the client's IP as failed before the password or code is checked.
+// While logins are held back after too many failures it answers 429 with Retry-After and returns false.
+func reserveLogin(c *gin.Context, email string, userID *uint) bool {
+	wait, err := loginguard.Default.Reserve(c.Request.Context(), email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not check login attempts"})
+		return false
+	}
+	if wait <= 0 {
+		return true
+	}
+
+	// Attempts while locked are audited but don't extend the lock
+	auditLoginFailure(c, email, userID, loginFailedLocked)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "Too many failed login attempts, please wait before trying again"})
+	return false
+}
+
+// refundLogin takes back a login reserved by reserveLogin that turned out not to be a wrong guess
+func refundLogin(c *gin.Context, email string) {
+	if err := loginguard.Default.Refund(c.Request.Context(), email, c.ClientIP()); err != nil {
+		log.Printf("Refunding login attempt for %q failed: %v", email, err)
+	}
+}
+
+// loginSucceeded clears the failed logins of an account
+func loginSucceeded(c *gin.Context, email string) {
+	if err := loginguard.Default.Succeed(c.Request.Context(), email, c.ClientIP()); err != nil {
+		log.Printf("Clearing failed logins for %q failed: %v", email, err)
+	}
+}
+
+// checkCurrentPassword checks the password a signed-in user enters again to confirm a sensitive change.
+// Wrong guesses count towards the login lockout, so a stolen access token can't be used to guess the password.
+// It writes the error response and returns false on failure.
+func checkCurrentPassword(c *gin.Context, user models.User, password string) bool {
+	if !reserveLogin(c, user.Email, &user.ID) {
+		return false
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		auditLoginFailure(c, user.Email, &user.ID, loginFailedCurrentPass)
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Current password is incorrect"})
+		return false
+	}
+	refundLogin(c, user.Email)
+	return true
+}
+
+// auditLoginFailure records a failed login; it was already counted by reserveLogin
+func auditLoginFailure(c *gin.Context, email string, userID *uint, reason string) {
+	err := config.DB.Create(&models.LoginFailure{
+		Email:     email,
+		UserID:    userID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Reason:    reason,
+	}).Error
+	if err != nil {
+		log.Printf("Recording failed login for %q failed: %v", email, err)
+	}
+}
@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// phonePattern accepts international and local notations such as "+44 20 7946 0958" or "(555) 123-4567"
+var phonePattern = regexp.MustCompile(`^\+?[0-9 ()\-]{6,20}$`)
+
+// UpdateProfileInput holds the profile fields a user can change; omitted fields stay as they are
+type UpdateProfileInput struct {
+	Name  *string `json:"name" binding:"omitempty,max=100"`
+	Phone *string `json:"phone"` // empty string removes the phone number
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type ChangeEmailInput struct {
+	NewEmail        string `json:"new_email" binding:"required,email"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+// GetMe godoc
+// @Summary      Get the current user
+// @Description  Returns the profile of the authenticated user
+// @Tags         account
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  UserResponse
+// @Failure      401,404 {object} ErrorResponse
+// @Router       /api/me [get]
+func GetMe(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, UserResponse{Data: toUserPayload(user)})
+}
+
+// UpdateMe godoc
+// @Summary      Update the current user's profile
+// @Description  Changes the name and phone number of the authenticated user. Email and password have their own endpoints.
+// @Tags         account
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  UpdateProfileInput  true  "Profile fields to change"
+// @Success      200  {object}  UserResponse
+// @Failure      400,401,404,500 {object} ErrorResponse
+// @Router       /api/me [patch]
+func UpdateMe(c *gin.Context) {
+	var input UpdateProfileInput
+	if err := bindStrictJSON(c, &input, "email", "password", "roles", "email_verified", "customer_group_id"); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Name != nil {
+		updates["name"] = strings.TrimSpace(*input.Name)
+	}
+	if input.Phone != nil {
+		phone := strings.TrimSpace(*input.Phone)
+		if phone != "" && !phonePattern.MatchString(phone) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid phone number"})
+			return
+		}
+		updates["phone"] = phone
+	}
+
+	if len(updates) > 0 {
+		if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not update profile"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, UserResponse{Data: toUserPayload(user)})
+}
+
+// ChangePassword godoc
+// @Summary      Change the current user's password
+// @Description  Sets a new password after checking the current one. Every other session of the account is logged out.
+// @Tags         account
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  ChangePasswordInput  true  "Current and new password"
+// @Success      200  {object}  MessageResponse
+// @Failure      400,401,404,429,500 {object} ErrorResponse
+// @Router       /api/me/password [post]
+func ChangePassword(c *gin.Context) {
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	if !checkCurrentPassword(c, user, input.CurrentPassword) {
+		return
+	}
+
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Unable to hash password"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("password", string(hashedPwd)).Error; err != nil {
+			return err
+		}
+		// The session making the change stays logged in
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND id <> ? AND revoked_at IS NULL", user.ID, c.GetUint("session_id")).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": revokedPasswordChange}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not change password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Password changed, other sessions have been logged out"})
+}
+
+// ChangeEmail godoc
+// @Summary      Change the current user's email address
+// @Description  Sends a verification link to the new address. The account keeps its current address until the link is used.
+// @Description  Like resending a verification email, this works once every EMAIL_VERIFICATION_RESEND_INTERVAL.
+// @Tags         account
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  ChangeEmailInput  true  "New email address and current password"
+// @Success      202  {object}  UserResponse
+// @Failure      400,401,404,409,429,500 {object} ErrorResponse
+// @Router       /api/me/email [post]
+func ChangeEmail(c *gin.Context) {
+	var input ChangeEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	if !checkCurrentPassword(c, user, input.CurrentPassword) {
+		return
+	}
+	if input.NewEmail == user.Email {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "This is already your email address"})
+		return
+	}
+
+	if verificationThrottled(c, user) {
+		return
+	}
+
+	var taken int64
+	if err := config.DB.Unscoped().Model(&models.User{}).Where("email = ?", input.NewEmail).Count(&taken).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not change email address"})
+		return
+	}
+	if taken > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "This email address is already used by another account"})
+		return
+	}
+
+	if err := config.DB.Model(&user).Update("pending_email", input.NewEmail).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not change email address"})
+		return
+	}
+
+	if err := sendVerificationEmail(c, user, input.NewEmail); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, UserResponse{Data: toUserPayload(user)})
+}
@@ -1,11 +1,15 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/Emibrown/E-commerce-API/config"
 	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/pricing"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type OrderRequest struct {
@@ -15,16 +19,26 @@ type OrderRequest struct {
 	} `json:"items"`
 }
 
+// orderLine is one product and quantity to be ordered
+type orderLine struct {
+	ProductID uint
+	Quantity  int
+}
+
+// errOrderProductNotFound is returned by placeOrder when a line refers to a missing or archived product
+var errOrderProductNotFound = errors.New("product not found")
+
 // CreateOrder godoc
 // @Summary      Create a new order
-// @Description  Places a new order for the authenticated user
+// @Description  Places a new order for the authenticated user. Retries sent with the same Idempotency-Key replay the original response.
 // @Tags         orders
 // @Security     BearerAuth
 // @Accept       json
 // @Produce      json
-// @Param        body body   OrderRequest  true  "Order Data"
+// @Param        Idempotency-Key header string        false  "Unique key identifying this order attempt"
+// @Param        body            body   OrderRequest  true   "Order Data"
 // @Success      201  {object} CreateOrderResponse
-// @Failure      400,401,500 {object} ErrorResponse
+// @Failure      400,401,409,422,500 {object} ErrorResponse
 // @Router       /api/orders [post]
 func CreateOrder(c *gin.Context) {
 	userId := c.GetUint("user_id")
@@ -35,55 +49,76 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	var orderItems []models.OrderItem
+	lines := make([]orderLine, 0, len(req.Items))
 	for _, item := range req.Items {
-		var product models.Product
-		if err := config.DB.First(&product, item.ProductID).Error; err != nil {
+		lines = append(lines, orderLine{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+
+	order, err := placeOrder(userId, lines)
+	if err != nil {
+		if errors.Is(err, errOrderProductNotFound) {
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Product not found"})
 			return
 		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create order"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, CreateOrderResponse{
+		Data: toOrderPayload(order),
+	})
+}
+
+// placeOrder creates a Pending order for userId, snapshotting each product's details and the
+// unit price the user pays for it (see pricing.UnitPrice)
+func placeOrder(userId uint, lines []orderLine) (models.Order, error) {
+	var user models.User
+	if err := config.DB.First(&user, userId).Error; err != nil {
+		return models.Order{}, err
+	}
+
+	var orderItems []models.OrderItem
+	var total float64
+	for _, line := range lines {
+		var product models.Product
+		if err := config.DB.First(&product, line.ProductID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.Order{}, errOrderProductNotFound
+			}
+			return models.Order{}, err
+		}
+
+		price, err := pricing.Resolve(config.DB, product, user.CustomerGroupID, line.Quantity)
+		if err != nil {
+			return models.Order{}, err
+		}
 
 		orderItems = append(orderItems, models.OrderItem{
-			ProductID: product.ID,
-			Quantity:  item.Quantity,
-			Price:     product.Price,
+			ProductID:          product.ID,
+			Quantity:           line.Quantity,
+			Price:              price,
+			ProductName:        product.Name,
+			ProductSKU:         product.SKU,
+			ProductDescription: product.Description,
+			ProductAttributes:  product.Attributes,
 		})
+		total += price * float64(line.Quantity)
 	}
 
 	order := models.Order{
 		UserID:   userId,
 		Products: orderItems,
 		Status:   models.Pending,
+		Total:    total,
+		History: []models.OrderStatusHistory{
+			{Status: models.Pending, ChangedBy: userId},
+		},
 	}
 
 	if err := config.DB.Create(&order).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create order"})
-		return
-	}
-
-	config.DB.Preload("Products.Product").First(&order, order.ID)
-
-	// Prepare response payload
-	itemPayloads := make([]OrderItemPayload, 0, len(orderItems))
-	for _, oi := range orderItems {
-		itemPayloads = append(itemPayloads, OrderItemPayload{
-			ProductID:   oi.ProductID,
-			Name:        oi.Product.Name,
-			Description: oi.Product.Description,
-			Quantity:    oi.Quantity,
-			Price:       oi.Price,
-		})
+		return models.Order{}, err
 	}
-	c.JSON(http.StatusCreated, CreateOrderResponse{
-		Data: OrderPayload{
-			ID:        order.ID,
-			UserID:    order.UserID,
-			Status:    string(order.Status),
-			Products:  itemPayloads,
-			CreatedAt: order.CreatedAt,
-			UpdatedAt: order.UpdatedAt,
-		},
-	})
+	return order, nil
 }
 
 // GetOrders godoc
@@ -99,8 +134,7 @@ func GetOrders(c *gin.Context) {
 	userId := c.GetUint("user_id")
 
 	var orders []models.Order
-	// Preload both the OrderItems ("Products") and each OrderItem's "Product"
-	if err := config.DB.Preload("Products.Product").
+	if err := config.DB.Preload("Products").
 		Where("user_id = ?", userId).
 		Find(&orders).Error; err != nil {
 
@@ -111,46 +145,66 @@ func GetOrders(c *gin.Context) {
 	// Convert to payload
 	var orderPayloads []OrderPayload
 	for _, o := range orders {
-		var itemPayloads []OrderItemPayload
-		for _, item := range o.Products {
-			itemPayloads = append(itemPayloads, OrderItemPayload{
-				ProductID:   item.ProductID,
-				Name:        item.Product.Name,
-				Description: item.Product.Description,
-				Quantity:    item.Quantity,
-				Price:       item.Price,
-			})
-		}
-
-		orderPayloads = append(orderPayloads, OrderPayload{
-			ID:        o.ID,
-			UserID:    o.UserID,
-			Status:    string(o.Status),
-			CreatedAt: o.CreatedAt,
-			UpdatedAt: o.UpdatedAt,
-			Products:  itemPayloads,
-		})
+		orderPayloads = append(orderPayloads, toOrderPayload(o))
 	}
 
 	c.JSON(http.StatusOK, GetOrdersResponse{Data: orderPayloads})
 }
 
+// GetOrderByID godoc
+// @Summary      Get one of the authenticated user's orders
+// @Description  Returns a single order belonging to the logged-in user, including its items, total and status timeline.
+// @Description  The ETag header carries the order version for use with If-Match.
+// @Tags         orders
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Order ID"
+// @Success      200  {object} OrderDetailResponse
+// @Failure      400,401,404 {object} ErrorResponse
+// @Router       /api/orders/{id} [get]
+func GetOrderByID(c *gin.Context) {
+	userId := c.GetUint("user_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	// Scoping by user_id means other users' orders are indistinguishable from missing ones
+	var order models.Order
+	if err := config.DB.Preload("Products").
+		Preload("History", orderHistoryByDate).
+		Where("id = ? AND user_id = ?", id, userId).
+		First(&order).Error; err != nil {
+
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Order not found"})
+		return
+	}
+
+	setETag(c, order.Version)
+	c.JSON(http.StatusOK, OrderDetailResponse{
+		Data: toOrderDetailPayload(order),
+	})
+}
+
 // CancelOrder godoc
 // @Summary      Cancel an order
 // @Description  Cancels an order if it's still in 'Pending' status
 // @Tags         orders
 // @Security     BearerAuth
 // @Produce      json
-// @Param        id   path      int  true  "Order ID"
+// @Param        id        path      int     true   "Order ID"
+// @Param        If-Match  header    string  false  "ETag of the order version being cancelled"
 // @Success      200  {object} CancelOrderResponse
-// @Failure      400,401,404,500 {object} ErrorResponse
+// @Failure      400,401,404,412,500 {object} ErrorResponse
 // @Router       /api/orders/{id}/cancel [put]
 func CancelOrder(c *gin.Context) {
 	userId := c.GetUint("user_id")
 	orderID := c.Param("id")
 
 	var order models.Order
-	if err := config.DB.Preload("Products.Product").
+	if err := config.DB.Preload("Products").
 		Where("id = ? AND user_id = ?", orderID, userId).
 		First(&order).Error; err != nil {
 
@@ -163,12 +217,22 @@ func CancelOrder(c *gin.Context) {
 		return
 	}
 
-	order.Status = models.Cancelled
-	if err := config.DB.Save(&order).Error; err != nil {
+	if !ifMatchSatisfied(c, order.Version) {
+		c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Order has been modified since it was fetched"})
+		return
+	}
+
+	if err := changeOrderStatus(&order, models.Cancelled, userId); err != nil {
+		if errors.Is(err, errStaleVersion) {
+			c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Order has been modified since it was fetched"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to cancel order"})
 		return
 	}
 
+	setETag(c, order.Version)
+
 	c.JSON(http.StatusOK, CancelOrderResponse{
 		Message: "Order cancelled",
 	})
@@ -180,10 +244,11 @@ func CancelOrder(c *gin.Context) {
 // @Tags         orders
 // @Security     BearerAuth
 // @Produce      json
-// @Param        id     path   int      true  "Order ID"
-// @Param        status query  string   true  "New Status (Shipped|Completed|Cancelled)"
+// @Param        id       path   int      true   "Order ID"
+// @Param        status   query  string   true   "New Status (Shipped|Completed|Cancelled)"
+// @Param        If-Match header string   false  "ETag of the order version being updated"
 // @Success      200    {object} UpdateOrderStatusResponse
-// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Failure      400,401,403,404,412,500 {object} ErrorResponse
 // @Router       /api/admin/orders/{id}/status [put]
 func UpdateOrderStatus(c *gin.Context) {
 	orderID := c.Param("id")
@@ -203,34 +268,103 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	order.Status = models.OrderStatus(newStatus)
-	if err := config.DB.Save(&order).Error; err != nil {
+	if !ifMatchSatisfied(c, order.Version) {
+		c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Order has been modified since it was fetched"})
+		return
+	}
+
+	if err := changeOrderStatus(&order, models.OrderStatus(newStatus), c.GetUint("user_id")); err != nil {
+		if errors.Is(err, errStaleVersion) {
+			c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Order has been modified since it was fetched"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update order status"})
 		return
 	}
 
-	config.DB.Preload("Products.Product").First(&order, order.ID)
+	setETag(c, order.Version)
+
+	c.JSON(http.StatusOK, UpdateOrderStatusResponse{
+		Data: toOrderPayload(order),
+	})
+}
+
+// changeOrderStatus saves the new status and appends it to the order's history.
+// It returns errStaleVersion if the order changed since it was loaded.
+func changeOrderStatus(order *models.Order, status models.OrderStatus, changedBy uint) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND version = ?", order.ID, order.Version).
+			Updates(map[string]interface{}{
+				"status":  status,
+				"version": gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errStaleVersion
+		}
+		order.Status = status
+		order.Version++
+
+		return tx.Create(&models.OrderStatusHistory{
+			OrderID:   order.ID,
+			Status:    status,
+			ChangedBy: changedBy,
+		}).Error
+	})
+}
 
-	// Build response payload
-	var itemPayloads []OrderItemPayload
+// toOrderPayload converts an order (with Products preloaded) into its response payload
+func toOrderPayload(order models.Order) OrderPayload {
+	itemPayloads := make([]OrderItemPayload, 0, len(order.Products))
 	for _, item := range order.Products {
 		itemPayloads = append(itemPayloads, OrderItemPayload{
 			ProductID:   item.ProductID,
-			Name:        item.Product.Name,
-			Description: item.Product.Description,
+			SKU:         item.ProductSKU,
+			Name:        item.ProductName,
+			Description: item.ProductDescription,
+			Attributes:  item.ProductAttributes,
 			Quantity:    item.Quantity,
 			Price:       item.Price,
 		})
 	}
 
-	c.JSON(http.StatusOK, UpdateOrderStatusResponse{
-		Data: OrderPayload{
-			ID:        order.ID,
-			UserID:    order.UserID,
-			Status:    string(order.Status),
-			Products:  itemPayloads,
-			CreatedAt: order.CreatedAt,
-			UpdatedAt: order.UpdatedAt,
-		},
-	})
+	return OrderPayload{
+		ID:        order.ID,
+		UserID:    order.UserID,
+		Status:    string(order.Status),
+		Total:     order.Total,
+		Version:   order.Version,
+		Products:  itemPayloads,
+		CreatedAt: order.CreatedAt,
+		UpdatedAt: order.UpdatedAt,
+	}
+}
+
+// toOrderDetailPayload converts an order (with Products and History preloaded) into its detail payload
+func toOrderDetailPayload(order models.Order) OrderDetailPayload {
+	return OrderDetailPayload{
+		OrderPayload: toOrderPayload(order),
+		History:      toOrderHistoryPayloads(order.History),
+	}
+}
+
+// orderHistoryByDate is a Preload condition returning status history oldest first
+func orderHistoryByDate(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at ASC")
+}
+
+// toOrderHistoryPayloads converts an order's status history into response payloads
+func toOrderHistoryPayloads(history []models.OrderStatusHistory) []OrderStatusChangePayload {
+	payloads := make([]OrderStatusChangePayload, 0, len(history))
+	for _, h := range history {
+		payloads = append(payloads, OrderStatusChangePayload{
+			Status:    string(h.Status),
+			ChangedBy: h.ChangedBy,
+			ChangedAt: h.CreatedAt,
+		})
+	}
+	return payloads
 }
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/loginguard"
+	"github.com/Emibrown/E-commerce-API/mailer"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPasswordResetTTL      = time.Hour
+	defaultPasswordResetInterval = time.Minute
+)
+
+// passwordResetIPPolicy limits how many password resets one client can request,
+// so the endpoint can't be used to flood mailboxes
+var passwordResetIPPolicy = loginguard.Policy{FreeFailures: 10, BaseDelay: time.Minute, MaxFailures: 30, Lockout: time.Hour, Window: time.Hour}
+
+// errMailDelivery wraps failures of the mailer
+var errMailDelivery = errors.New("mail delivery failed")
+
+type PasswordResetRequestInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type PasswordResetConfirmInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// RequestPasswordReset godoc
+// @Summary      Request a password reset
+// @Description  Emails a single-use password reset link to the address if it belongs to an account.
+// @Description  The response is the same whether or not the account exists, and the email is sent in the background.
+// @Description  An account gets at most one link per PASSWORD_RESET_INTERVAL; clients sending too many requests get a 429.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   PasswordResetRequestInput  true  "Account email"
+// @Success      202   {object} MessageResponse
+// @Failure      400,429,500 {object} ErrorResponse
+// @Router       /api/auth/password-reset [post]
+func RequestPasswordReset(c *gin.Context) {
+	var input PasswordResetRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	wait, err := loginguard.Default.Hit(c.Request.Context(), "password-reset-ip:"+c.ClientIP(), passwordResetIPPolicy)
+	if err != nil {
+		log.Printf("Checking password reset limit failed: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not start password reset"})
+		return
+	}
+	if wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{Error: "Too many password reset requests, please try again later"})
+		return
+	}
+
+	response := MessageResponse{Message: "If an account exists for this email, a password reset link has been sent"}
+
+	var user models.User
+	if err := config.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+
+	// Creating the token and talking to the mail server happen after responding, so the response
+	// takes as long for unknown addresses as for known ones
+	go func() {
+		var last models.PasswordResetToken
+		err := config.DB.Where("user_id = ?", user.ID).Order("created_at DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			log.Printf("Checking password reset interval of user %d failed: %v", user.ID, err)
+			return
+		}
+		if last.ID != 0 && time.Since(last.CreatedAt) < envDuration("PASSWORD_RESET_INTERVAL", defaultPasswordResetInterval) {
+			return
+		}
+
+		msg := "Someone asked to reset the password of your account.\n\n" +
+			"Open this link within %s to choose a new password:\n\n%s\n\n" +
+			"If it wasn't you, you can ignore this email; your password stays the same.\n"
+		if err := sendPasswordResetEmail(context.Background(), user, msg); err != nil && !errors.Is(err, errMailDelivery) {
+			log.Printf("Starting password reset of user %d failed: %v", user.ID, err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// ConfirmPasswordReset godoc
+// @Summary      Set a new password with a reset token
+// @Description  Sets a new password using the token from a password reset email. The token works once,
+// @Description  and every existing session of the account is logged out.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   PasswordResetConfirmInput  true  "Reset token and new password"
+// @Success      200   {object} MessageResponse
+// @Failure      400,500 {object} ErrorResponse
+// @Router       /api/auth/password-reset/confirm [post]
+func ConfirmPasswordReset(c *gin.Context) {
+	var input PasswordResetConfirmInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var resetToken models.PasswordResetToken
+	err := config.DB.Where("token_hash = ?", hashToken(input.Token)).First(&resetToken).Error
+	if err != nil || resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid or expired reset token"})
+		return
+	}
+
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Unable to hash password"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&resetToken).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenUsed
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", resetToken.UserID).Updates(map[string]interface{}{
+			"password":            string(hashedPwd),
+			"must_reset_password": false,
+		}).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, resetToken.UserID, revokedPasswordReset)
+	})
+	if err != nil {
+		if errors.Is(err, errResetTokenUsed) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid or expired reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not reset password"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Password has been reset, please log in again"})
+}
+
+// sendPasswordResetEmail creates a reset token for user, invalidating older ones, and mails the link.
+// body is a format string receiving the link's lifetime and the link itself.
+func sendPasswordResetEmail(ctx context.Context, user models.User, body string) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	ttl := envDuration("PASSWORD_RESET_TOKEN_TTL", defaultPasswordResetTTL)
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the newest link works
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    fmt.Sprintf(body, ttl, frontendLink("PASSWORD_RESET_URL", "/reset-password", token)),
+	}
+	if err := mailer.Default.Send(ctx, msg); err != nil {
+		log.Printf("Sending password reset email to user %d failed: %v", user.ID, err)
+		return fmt.Errorf("%w: %v", errMailDelivery, err)
+	}
+	return nil
+}
+
+// frontendLink builds a link into the storefront carrying token as a query parameter.
+// The page comes from the env variable name, or defaults to STORE_URL followed by path.
+func frontendLink(name, path, token string) string {
+	base := os.Getenv(name)
+	if base == "" {
+		base = strings.TrimRight(os.Getenv("STORE_URL"), "/") + path
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/pricing"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetPriceHistory godoc
+// @Summary      Get a product's price history
+// @Description  Returns the changes of a product's regular and sale price, newest first (admin only)
+// @Tags         pricing
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id         path   int     true   "Product ID"
+// @Param        kind       query  string  false  "Only changes of this price (regular|sale)"
+// @Param        page       query  int     false  "Page number (default 1)"
+// @Param        page_size  query  int     false  "Page size (default 20, max 100)"
+// @Success      200  {object}  PriceHistoryResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/price-history [get]
+func GetPriceHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Unscoped().First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	query := config.DB.Model(&models.PriceHistory{}).Where("product_id = ?", product.ID)
+	if kind := c.Query("kind"); kind != "" {
+		if !isValidPriceKind(kind) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "kind must be regular or sale"})
+			return
+		}
+		query = query.Where("kind = ?", kind)
+	}
+
+	page, pageSize := paginationParams(c)
+
+	var count int64
+	var entries []models.PriceHistory
+	if err := query.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch price history"})
+		return
+	}
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch price history"})
+		return
+	}
+
+	payloads := make([]PriceHistoryPayload, 0, len(entries))
+	for _, e := range entries {
+		payloads = append(payloads, PriceHistoryPayload{
+			ID:        e.ID,
+			Kind:      string(e.Kind),
+			OldPrice:  e.OldPrice,
+			NewPrice:  e.NewPrice,
+			Source:    e.Source,
+			ChangedBy: e.ChangedBy,
+			ChangedAt: e.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, PriceHistoryResponse{
+		Data: payloads,
+		Pagination: PaginationPayload{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: count,
+		},
+	})
+}
+
+// GetScheduledPrices godoc
+// @Summary      List a product's scheduled prices
+// @Description  Returns the upcoming, running and past scheduled price changes of a product (admin only)
+// @Tags         pricing
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id      path   int     true   "Product ID"
+// @Param        status  query  string  false  "Scheduled, Active, Applied, Ended or Cancelled"
+// @Success      200  {object}  ScheduledPricesResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/scheduled-prices [get]
+func GetScheduledPrices(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Unscoped().First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	query := config.DB.Where("product_id = ?", product.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var schedules []models.ScheduledPrice
+	if err := query.Order("starts_at ASC, id ASC").Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch scheduled prices"})
+		return
+	}
+
+	payloads := make([]ScheduledPricePayload, 0, len(schedules))
+	for _, s := range schedules {
+		payloads = append(payloads, toScheduledPricePayload(s))
+	}
+
+	c.JSON(http.StatusOK, ScheduledPricesResponse{Data: payloads})
+}
+
+// SchedulePrice godoc
+// @Summary      Schedule a price change
+// @Description  Schedules a new regular price from starts_at, or a sale price from starts_at until the optional ends_at (admin only).
+// @Description  A start time in the past takes effect on the scheduler's next run.
+// @Tags         pricing
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int                  true  "Product ID"
+// @Param        body  body  SchedulePriceInput  true  "Scheduled price"
+// @Success      201  {object}  ScheduledPriceResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/scheduled-prices [post]
+func SchedulePrice(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	var input SchedulePriceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	kind := models.PriceKind(input.Kind)
+	if kind == models.PriceRegular && input.EndsAt != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Only sale prices can have an end time"})
+		return
+	}
+	if input.EndsAt != nil && !input.EndsAt.After(input.StartsAt) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "ends_at must be after starts_at"})
+		return
+	}
+	if kind == models.PriceSale && input.Price >= product.Price {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "A sale price must be lower than the regular price"})
+		return
+	}
+
+	schedule := models.ScheduledPrice{
+		ProductID: product.ID,
+		Kind:      kind,
+		Price:     input.Price,
+		StartsAt:  input.StartsAt,
+		EndsAt:    input.EndsAt,
+		Status:    models.ScheduleScheduled,
+		CreatedBy: c.GetUint("user_id"),
+	}
+	if err := config.DB.Create(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not schedule price"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ScheduledPriceResponse{Data: toScheduledPricePayload(schedule)})
+}
+
+// CancelScheduledPrice godoc
+// @Summary      Cancel a scheduled price
+// @Description  Cancels a price change that hasn't started yet, or ends a running sale right away (admin only)
+// @Tags         pricing
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id           path  int  true  "Product ID"
+// @Param        schedule_id  path  int  true  "Scheduled price ID"
+// @Success      200  {object}  ScheduledPriceResponse
+// @Failure      401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/scheduled-prices/{schedule_id} [delete]
+func CancelScheduledPrice(c *gin.Context) {
+	var schedule models.ScheduledPrice
+	if err := config.DB.Where("id = ? AND product_id = ?", c.Param("schedule_id"), c.Param("id")).First(&schedule).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Scheduled price not found"})
+		return
+	}
+
+	var err error
+	switch schedule.Status {
+	case models.ScheduleScheduled:
+		result := config.DB.Model(&schedule).Where("status = ?", models.ScheduleScheduled).Update("status", models.ScheduleCancelled)
+		err = result.Error
+		if err == nil && result.RowsAffected == 0 {
+			err = errStaleVersion
+		}
+	case models.ScheduleActive:
+		err = config.DB.Transaction(func(tx *gorm.DB) error { return pricing.EndSale(tx, schedule) })
+		if err == nil {
+			schedule.Status = models.ScheduleEnded
+		}
+	default:
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Only scheduled prices and running sales can be cancelled"})
+		return
+	}
+	if err != nil {
+		if errors.Is(err, errStaleVersion) || errors.Is(err, pricing.ErrAlreadyHandled) {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "The scheduled price has just been applied"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to cancel scheduled price"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ScheduledPriceResponse{Data: toScheduledPricePayload(schedule)})
+}
+
+// isValidPriceKind reports whether s is one of the known price kinds
+func isValidPriceKind(s string) bool {
+	switch models.PriceKind(s) {
+	case models.PriceRegular, models.PriceSale:
+		return true
+	}
+	return false
+}
+
+// toScheduledPricePayload converts a scheduled price into its response payload
+func toScheduledPricePayload(s models.ScheduledPrice) ScheduledPricePayload {
+	return ScheduledPricePayload{
+		ID:        s.ID,
+		ProductID: s.ProductID,
+		Kind:      string(s.Kind),
+		Price:     s.Price,
+		StartsAt:  s.StartsAt,
+		EndsAt:    s.EndsAt,
+		Status:    string(s.Status),
+		CreatedBy: s.CreatedBy,
+		CreatedAt: s.CreatedAt,
+	}
+}
@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/Emibrown/E-commerce-API/config"
 	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/pricing"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CreateProduct godoc
@@ -18,7 +22,7 @@ import (
 // @Produce      json
 // @Param        body  body   CreateProductInput         true  "Create Product Input"
 // @Success      201   {object} CreateProductResponse
-// @Failure      400,401,403,500 {object} ErrorResponse
+// @Failure      400,401,403,409,500 {object} ErrorResponse
 // @Router       /api/admin/products [post]
 func CreateProduct(c *gin.Context) {
 	var input CreateProductInput
@@ -27,42 +31,57 @@ func CreateProduct(c *gin.Context) {
 		return
 	}
 
+	if input.SKU != "" && skuTaken(input.SKU, 0) {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "A product with this SKU already exists"})
+		return
+	}
+
 	// Convert CreateProductInput into a models.Product
 	product := models.Product{
+		SKU:         input.SKU,
+		GTIN:        input.GTIN,
 		Name:        input.Name,
 		Description: input.Description,
+		Attributes:  input.Attributes,
 		Price:       input.Price,
 	}
 
-	if err := config.DB.Create(&product).Error; err != nil {
+	adminID := c.GetUint("user_id")
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		return pricing.RecordChange(tx, product.ID, models.PriceRegular, nil, &product.Price, models.PriceSourceAdmin, &adminID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create product"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, CreateProductResponse{
-		Data: ProductPayload{
-			ID:          product.ID,
-			Name:        product.Name,
-			Description: product.Description,
-			Price:       product.Price,
-			CreatedAt:   product.CreatedAt,
-			UpdatedAt:   product.UpdatedAt,
-		},
+		Data: toProductPayload(product),
 	})
 }
 
 // GetProducts godoc
 // @Summary      Get all products
-// @Description  Returns a list of all products (admin only endpoint in this example)
+// @Description  Returns a list of all products (admin only endpoint in this example). Archived products are hidden unless requested.
 // @Tags         products
 // @Security     BearerAuth
 // @Produce      json
+// @Param        archived  query  string  false  "Archived products: 'true' lists only archived ones, 'all' includes them"
 // @Success      200   {object} GetProductsResponse
-// @Failure      500   {object} ErrorResponse
+// @Failure      400,500   {object} ErrorResponse
 // @Router       /api/admin/products [get]
 func GetProducts(c *gin.Context) {
+	scope, err := productListScope(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
 	var products []models.Product
-	if err := config.DB.Find(&products).Error; err != nil {
+	if err := config.DB.Scopes(scope).Preload("Images", imagesByPosition).Find(&products).Error; err != nil {
 		// If there's a real DB error (e.g., connection issue) then respond 500
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
@@ -70,14 +89,7 @@ func GetProducts(c *gin.Context) {
 
 	var payloads []ProductPayload
 	for _, p := range products {
-		payloads = append(payloads, ProductPayload{
-			ID:          p.ID,
-			Name:        p.Name,
-			Description: p.Description,
-			Price:       p.Price,
-			CreatedAt:   p.CreatedAt,
-			UpdatedAt:   p.UpdatedAt,
-		})
+		payloads = append(payloads, toProductPayload(p))
 	}
 
 	c.JSON(http.StatusOK, GetProductsResponse{Data: payloads})
@@ -85,7 +97,7 @@ func GetProducts(c *gin.Context) {
 
 // GetProductByID godoc
 // @Summary      Get a product by its ID
-// @Description  Returns a single product (admin only endpoint in this example)
+// @Description  Returns a single product (admin only endpoint in this example). The ETag header carries the product version for use with If-Match.
 // @Tags         products
 // @Security     BearerAuth
 // @Produce      json
@@ -101,35 +113,32 @@ func GetProductByID(c *gin.Context) {
 	}
 
 	var product models.Product
-	if err := config.DB.First(&product, id).Error; err != nil {
+	if err := config.DB.Preload("Images", imagesByPosition).First(&product, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Product not found"})
 		return
 	}
 
-	payload := ProductPayload{
-		ID:          product.ID,
-		Name:        product.Name,
-		Description: product.Description,
-		Price:       product.Price,
-		CreatedAt:   product.CreatedAt,
-		UpdatedAt:   product.UpdatedAt,
-	}
+	payload := toProductPayload(product)
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, SingleProductResponse{Data: payload})
 }
 
 // UpdateProduct godoc
 // @Summary      Update a product
-// @Description  Updates an existing product (admin only)
+// @Description  Updates an existing product (admin only). Only the fields present in the body are changed; unknown or read-only fields are rejected.
+// @Description  Send the ETag from GET as If-Match to make sure nobody else changed the product in the meantime.
 // @Tags         products
 // @Security     BearerAuth
 // @Accept       json
 // @Produce      json
-// @Param        id   path      int              true  "Product ID"
-// @Param        body body      models.Product   true  "Product Data"
+// @Param        id        path      int                  true   "Product ID"
+// @Param        If-Match  header    string               false  "ETag of the version being edited"
+// @Param        body      body      UpdateProductInput   true   "Fields to change"
 // @Success      200  {object}  UpdateProductResponse
-// @Failure      400,404,500 {object} ErrorResponse
+// @Failure      400,404,409,412,500 {object} ErrorResponse
 // @Router       /api/admin/products/{id} [put]
+// @Router       /api/admin/products/{id} [patch]
 func UpdateProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -143,31 +152,90 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	if err := c.ShouldBindJSON(&product); err != nil {
+	if !ifMatchSatisfied(c, product.Version) {
+		c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Product has been modified since it was fetched"})
+		return
+	}
+
+	var input UpdateProductInput
+	if err := bindStrictJSON(c, &input, productImmutableFields...); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	if err := config.DB.Save(&product).Error; err != nil {
+	updates := map[string]interface{}{}
+	if input.SKU != nil {
+		if *input.SKU != "" && skuTaken(*input.SKU, product.ID) {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "A product with this SKU already exists"})
+			return
+		}
+		updates["sku"] = *input.SKU
+	}
+	if input.GTIN != nil {
+		updates["gtin"] = *input.GTIN
+	}
+	if input.Name != nil {
+		if strings.TrimSpace(*input.Name) == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Name cannot be empty"})
+			return
+		}
+		updates["name"] = *input.Name
+	}
+	if input.Description != nil {
+		updates["description"] = *input.Description
+	}
+	if input.Attributes != nil {
+		updates["attributes"] = models.Attributes(*input.Attributes)
+	}
+	if input.Price != nil {
+		updates["price"] = *input.Price
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "No fields to update"})
+		return
+	}
+
+	// Only write if nobody else has bumped the version since we read it
+	oldPrice := product.Price
+	adminID := c.GetUint("user_id")
+	updates["version"] = gorm.Expr("version + 1")
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&product).Where("version = ?", product.Version).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errStaleVersion
+		}
+		if input.Price == nil {
+			return nil
+		}
+		return pricing.RecordChange(tx, product.ID, models.PriceRegular, &oldPrice, input.Price, models.PriceSourceAdmin, &adminID)
+	})
+	if err != nil {
+		if errors.Is(err, errStaleVersion) {
+			c.JSON(http.StatusPreconditionFailed, ErrorResponse{Error: "Product has been modified since it was fetched"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update product"})
+		return
+	}
+
+	if err := config.DB.Preload("Images", imagesByPosition).First(&product, product.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update product"})
 		return
 	}
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, UpdateProductResponse{
-		Data: ProductPayload{
-			ID:          product.ID,
-			Name:        product.Name,
-			Description: product.Description,
-			Price:       product.Price,
-			CreatedAt:   product.CreatedAt,
-			UpdatedAt:   product.UpdatedAt,
-		},
+		Data: toProductPayload(product),
 	})
 }
 
 // DeleteProduct godoc
 // @Summary      Delete a product
-// @Description  Deletes an existing product (admin only)
+// @Description  Archives an existing product (admin only). It disappears from the catalog but existing orders keep referencing it, and it can be restored.
 // @Tags         products
 // @Security     BearerAuth
 // @Produce      json
@@ -194,6 +262,82 @@ func DeleteProduct(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, DeleteProductResponse{
-		Message: "Product deleted",
+		Message: "Product archived",
 	})
 }
+
+// RestoreProduct godoc
+// @Summary      Restore an archived product
+// @Description  Brings an archived product back into the catalog (admin only)
+// @Tags         products
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Product ID"
+// @Success      200  {object}  SingleProductResponse
+// @Failure      400,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/restore [post]
+func RestoreProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Unscoped().Preload("Images", imagesByPosition).Where("deleted_at IS NOT NULL").First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Archived product not found"})
+		return
+	}
+
+	if err := config.DB.Unscoped().Model(&product).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to restore product"})
+		return
+	}
+	product.DeletedAt = gorm.DeletedAt{}
+
+	c.JSON(http.StatusOK, SingleProductResponse{Data: toProductPayload(product)})
+}
+
+// toProductPayload converts a product into its response payload
+func toProductPayload(product models.Product) ProductPayload {
+	payload := ProductPayload{
+		ID:            product.ID,
+		SKU:           product.SKU,
+		GTIN:          product.GTIN,
+		Name:          product.Name,
+		Description:   product.Description,
+		Attributes:    product.Attributes,
+		Images:        toProductImagePayloads(product.Images),
+		Price:         product.Price,
+		SalePrice:     product.SalePrice,
+		RatingAverage: product.RatingAverage,
+		RatingCount:   product.RatingCount,
+		Version:       product.Version,
+		CreatedAt:     product.CreatedAt,
+		UpdatedAt:     product.UpdatedAt,
+	}
+	if product.DeletedAt.Valid {
+		payload.ArchivedAt = &product.DeletedAt.Time
+	}
+	return payload
+}
+
+// productListScope builds the query scope for product listings from the request's filters
+func productListScope(c *gin.Context) (func(*gorm.DB) *gorm.DB, error) {
+	switch c.Query("archived") {
+	case "", "false":
+		return func(db *gorm.DB) *gorm.DB { return db }, nil
+	case "true":
+		return func(db *gorm.DB) *gorm.DB { return db.Unscoped().Where("deleted_at IS NOT NULL") }, nil
+	case "all":
+		return func(db *gorm.DB) *gorm.DB { return db.Unscoped() }, nil
+	}
+	return nil, errors.New("archived must be one of true, false or all")
+}
+
+// skuTaken reports whether another product (other than excludeID, archived ones included) already uses sku
+func skuTaken(sku string, excludeID uint) bool {
+	var count int64
+	config.DB.Unscoped().Model(&models.Product{}).Where("sku = ? AND id <> ?", sku, excludeID).Count(&count)
+	return count > 0
+}
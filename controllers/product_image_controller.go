@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/media"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/storage"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxImageFileSize limits product image uploads
+const maxImageFileSize = 10 << 20
+
+// UploadProductImage godoc
+// @Summary      Upload a product image
+// @Description  Stores a JPEG, PNG or GIF image for a product and generates its thumbnails (admin only).
+// @Description  Without a position the image is added after the existing ones. Images over 25 megapixels are rejected.
+// @Tags         product-images
+// @Security     BearerAuth
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id        path      int     true   "Product ID"
+// @Param        image     formData  file    true   "Image file"
+// @Param        alt_text  formData  string  false  "Alternative text"
+// @Param        position  formData  int     false  "Display position (ascending)"
+// @Success      201  {object}  ProductImageResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/images [post]
+func UploadProductImage(c *gin.Context) {
+	product, ok := findProductForImages(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "An image file is required"})
+		return
+	}
+	if fileHeader.Size > maxImageFileSize {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Image is too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Could not read image"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Could not read image"})
+		return
+	}
+
+	img, format, err := media.Decode(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	position, err := nextImagePosition(product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not save image"})
+		return
+	}
+	if p := c.PostForm("position"); p != "" {
+		if position, err = strconv.Atoi(p); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid position"})
+			return
+		}
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not save image"})
+		return
+	}
+
+	image := models.ProductImage{
+		ProductID: product.ID,
+		Key:       fmt.Sprintf("products/%d/%s%s", product.ID, hex.EncodeToString(random), media.Extension(format)),
+		Format:    format,
+		Width:     img.Bounds().Dx(),
+		Height:    img.Bounds().Dy(),
+		AltText:   c.PostForm("alt_text"),
+		Position:  position,
+	}
+
+	ctx := c.Request.Context()
+	if err := storage.Default.Put(ctx, image.Key, bytes.NewReader(data), media.ContentType(format)); err != nil {
+		log.Printf("Storing product image failed: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not save image"})
+		return
+	}
+
+	for _, size := range media.ThumbnailSizes {
+		thumb, contentType, err := media.EncodeThumbnail(media.Thumbnail(img, size.Max), format)
+		if err == nil {
+			err = storage.Default.Put(ctx, media.ThumbnailKey(image.Key, format, size.Name), bytes.NewReader(thumb), contentType)
+		}
+		if err != nil {
+			log.Printf("Storing product image thumbnail failed: %v", err)
+			deleteImageFiles(ctx, image)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not save image"})
+			return
+		}
+	}
+
+	if err := config.DB.Create(&image).Error; err != nil {
+		deleteImageFiles(ctx, image)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not save image"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ProductImageResponse{Data: toProductImagePayload(image)})
+}
+
+// UpdateProductImage godoc
+// @Summary      Update a product image
+// @Description  Changes the alt text and/or position of a product image (admin only)
+// @Tags         product-images
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id        path  int                      true  "Product ID"
+// @Param        image_id  path  int                      true  "Image ID"
+// @Param        body      body  UpdateProductImageInput  true  "Fields to change"
+// @Success      200  {object}  ProductImageResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/images/{image_id} [patch]
+func UpdateProductImage(c *gin.Context) {
+	image, ok := findProductImage(c)
+	if !ok {
+		return
+	}
+
+	var input UpdateProductImageInput
+	if err := bindStrictJSON(c, &input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.AltText != nil {
+		updates["alt_text"] = *input.AltText
+	}
+	if input.Position != nil {
+		updates["position"] = *input.Position
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "No fields to update"})
+		return
+	}
+
+	if err := config.DB.Model(&image).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update image"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ProductImageResponse{Data: toProductImagePayload(image)})
+}
+
+// ReorderProductImages godoc
+// @Summary      Reorder product images
+// @Description  Sets the display order of all of a product's images at once (admin only)
+// @Tags         product-images
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int                         true  "Product ID"
+// @Param        body  body  ReorderProductImagesInput   true  "Every image ID of the product, in display order"
+// @Success      200  {object}  ProductImagesResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/images/order [put]
+func ReorderProductImages(c *gin.Context) {
+	product, ok := findProductForImages(c)
+	if !ok {
+		return
+	}
+
+	var input ReorderProductImagesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var images []models.ProductImage
+	if err := config.DB.Where("product_id = ?", product.ID).Find(&images).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to reorder images"})
+		return
+	}
+
+	known := make(map[uint]bool, len(images))
+	for _, img := range images {
+		known[img.ID] = true
+	}
+	if len(input.ImageIDs) != len(images) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "image_ids must list every image of the product exactly once"})
+		return
+	}
+	for _, id := range input.ImageIDs {
+		if !known[id] {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "image_ids must list every image of the product exactly once"})
+			return
+		}
+		delete(known, id)
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for position, id := range input.ImageIDs {
+			if err := tx.Model(&models.ProductImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to reorder images"})
+		return
+	}
+
+	config.DB.Scopes(imagesByPosition).Where("product_id = ?", product.ID).Find(&images)
+	c.JSON(http.StatusOK, ProductImagesResponse{Data: toProductImagePayloads(images)})
+}
+
+// DeleteProductImage godoc
+// @Summary      Delete a product image
+// @Description  Removes a product image and its thumbnails (admin only)
+// @Tags         product-images
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id        path  int  true  "Product ID"
+// @Param        image_id  path  int  true  "Image ID"
+// @Success      200  {object}  DeleteProductImageResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/products/{id}/images/{image_id} [delete]
+func DeleteProductImage(c *gin.Context) {
+	image, ok := findProductImage(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(&image).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete image"})
+		return
+	}
+	deleteImageFiles(c.Request.Context(), image)
+
+	c.JSON(http.StatusOK, DeleteProductImageResponse{Message: "Image deleted"})
+}
+
+// findProductForImages loads the product named by the :id param, responding with an error if there is none
+func findProductForImages(c *gin.Context) (models.Product, bool) {
+	var product models.Product
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return product, false
+	}
+
+	if err := config.DB.First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Product not found"})
+		return product, false
+	}
+	return product, true
+}
+
+// findProductImage loads the image named by the :image_id param, which must belong to the :id product
+func findProductImage(c *gin.Context) (models.ProductImage, bool) {
+	var image models.ProductImage
+
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return image, false
+	}
+	imageID, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid image ID"})
+		return image, false
+	}
+
+	err = config.DB.Where("id = ? AND product_id = ?", imageID, productID).First(&image).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Image not found"})
+		return image, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch image"})
+		return image, false
+	}
+	return image, true
+}
+
+// nextImagePosition is the position after the product's last image
+func nextImagePosition(productID uint) (int, error) {
+	var max *int
+	err := config.DB.Model(&models.ProductImage{}).
+		Where("product_id = ?", productID).
+		Select("MAX(position)").
+		Scan(&max).Error
+	if err != nil || max == nil {
+		return 0, err
+	}
+	return *max + 1, nil
+}
+
+// deleteImageFiles removes an image's original and thumbnails from storage, logging failures
+func deleteImageFiles(ctx context.Context, image models.ProductImage) {
+	keys := []string{image.Key}
+	for _, size := range media.ThumbnailSizes {
+		keys = append(keys, media.ThumbnailKey(image.Key, image.Format, size.Name))
+	}
+	for _, key := range keys {
+		if err := storage.Default.Delete(ctx, key); err != nil {
+			log.Printf("Deleting %s from storage failed: %v", key, err)
+		}
+	}
+}
+
+// imagesByPosition orders product images for display; usable with Scopes and as a Preload condition
+func imagesByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, id ASC")
+}
+
+// toProductImagePayload converts a product image into its response payload
+func toProductImagePayload(image models.ProductImage) ProductImagePayload {
+	thumbnails := make(map[string]string, len(media.ThumbnailSizes))
+	for _, size := range media.ThumbnailSizes {
+		thumbnails[size.Name] = storage.Default.URL(media.ThumbnailKey(image.Key, image.Format, size.Name))
+	}
+
+	return ProductImagePayload{
+		ID:         image.ID,
+		URL:        storage.Default.URL(image.Key),
+		Thumbnails: thumbnails,
+		AltText:    image.AltText,
+		Position:   image.Position,
+		Width:      image.Width,
+		Height:     image.Height,
+	}
+}
+
+// toProductImagePayloads converts product images into response payloads
+func toProductImagePayloads(images []models.ProductImage) []ProductImagePayload {
+	payloads := make([]ProductImagePayload, 0, len(images))
+	for _, img := range images {
+		payloads = append(payloads, toProductImagePayload(img))
+	}
+	return payloads
+}
@@ -6,14 +6,33 @@ import "time"
 
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code,omitempty"` // reason code of 401s from the auth middleware, e.g. token_expired
+}
+
+// ------------------ Pagination ------------------ //
+
+type PaginationPayload struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	TotalItems int64 `json:"total_items"`
 }
 
 // ------------------ Auth Response ------------------ //
 
 type UserPayload struct {
-	ID      uint   `json:"id"`
-	Email   string `json:"email"`
-	IsAdmin bool   `json:"is_admin"`
+	ID               uint     `json:"id"`
+	Email            string   `json:"email"`
+	PendingEmail     string   `json:"pending_email,omitempty"` // new address waiting for verification
+	Name             string   `json:"name"`
+	Phone            string   `json:"phone"`
+	Roles            []string `json:"roles"` // staff roles; empty for customers
+	EmailVerified    bool     `json:"email_verified"`
+	TwoFactorEnabled bool     `json:"two_factor_enabled"`
+	CustomerGroupID  *uint    `json:"customer_group_id"`
+}
+
+type UserResponse struct {
+	Data UserPayload `json:"data"`
 }
 
 // RegisterResponse is returned when a user registers successfully
@@ -22,20 +41,165 @@ type RegisterResponse struct {
 	User    UserPayload `json:"user"`
 }
 
-// LoginResponse is returned when user logs in successfully
+// LoginResponse is returned when user logs in successfully or refreshes their token
 type LoginResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // seconds until the access token expires
+}
+
+// TwoFactorChallengeResponse is returned by login instead of tokens when the account uses two-factor authentication
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"` // send with a code to /api/auth/login/2fa
+	ExpiresIn         int    `json:"expires_in"`      // seconds until the challenge expires
+}
+
+// LogoutResponse is a simple message for logout success
+type LogoutResponse struct {
+	Message string `json:"message"`
+}
+
+// MessageResponse is a plain confirmation message
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// ------------------ Two-Factor Response ------------------ //
+
+// TwoFactorSetupResponse carries a new authenticator secret
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"` // render as a QR code for authenticator apps
+}
+
+// RecoveryCodesResponse lists freshly created recovery codes, each usable once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ------------------ Admin User Response ------------------ //
+
+// AdminUserPayload is a user as seen by admins
+type AdminUserPayload struct {
+	UserPayload
+	DisabledAt        *time.Time `json:"disabled_at"`
+	DisabledReason    string     `json:"disabled_reason,omitempty"`
+	MustResetPassword bool       `json:"must_reset_password"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// AdminUserDetailPayload adds the user's order figures
+type AdminUserDetailPayload struct {
+	AdminUserPayload
+	OrderCount    int64      `json:"order_count"`
+	LifetimeSpend float64    `json:"lifetime_spend"` // total of all non-cancelled orders
+	LastOrderAt   *time.Time `json:"last_order_at"`
+
+	FailedLogins     int        `json:"failed_logins"`      // consecutive failures since the last successful login
+	LoginLockedUntil *time.Time `json:"login_locked_until"` // set while logins are held back
+}
+
+// LoginFailurePayload is one audited failed login
+type LoginFailurePayload struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	UserID    *uint     `json:"user_id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetLoginFailuresResponse struct {
+	Data       []LoginFailurePayload `json:"data"`
+	Pagination PaginationPayload     `json:"pagination"`
+}
+
+type AdminUserResponse struct {
+	Data AdminUserPayload `json:"data"`
+}
+
+type AdminUserDetailResponse struct {
+	Data AdminUserDetailPayload `json:"data"`
+}
+
+type AdminGetUsersResponse struct {
+	Data       []AdminUserPayload `json:"data"`
+	Pagination PaginationPayload  `json:"pagination"`
+}
+
+// ------------------ Staff Response ------------------ //
+
+type GetUsersResponse struct {
+	Data []UserPayload `json:"data"`
+}
+
+type RolePayload struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	BuiltIn     bool     `json:"built_in"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleResponse struct {
+	Data RolePayload `json:"data"`
+}
+
+type GetRolesResponse struct {
+	Data []RolePayload `json:"data"`
+}
+
+type PermissionsResponse struct {
+	Data []string `json:"data"`
+}
+
+type StaffInvitationPayload struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	Roles     []string  `json:"roles"`
+	InvitedBy uint      `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type StaffInvitationResponse struct {
+	Data StaffInvitationPayload `json:"data"`
+}
+
+type StaffInvitationsResponse struct {
+	Data []StaffInvitationPayload `json:"data"`
 }
 
 // ------------------ Product Response ------------------ //
 
 type ProductPayload struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID            uint                  `json:"id"`
+	SKU           string                `json:"sku"`
+	GTIN          string                `json:"gtin"`
+	Name          string                `json:"name"`
+	Description   string                `json:"description"`
+	Attributes    map[string]string     `json:"attributes"`
+	Images        []ProductImagePayload `json:"images"`
+	Price         float64               `json:"price"`
+	SalePrice     *float64              `json:"sale_price"`
+	RatingAverage float64               `json:"rating_average"`
+	RatingCount   int                   `json:"rating_count"`
+	Version       uint                  `json:"version"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+	ArchivedAt    *time.Time            `json:"archived_at,omitempty"`
+}
+
+type ProductImagePayload struct {
+	ID         uint              `json:"id"`
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"` // size name -> URL
+	AltText    string            `json:"alt_text"`
+	Position   int               `json:"position"`
+	Width      int               `json:"width"`
+	Height     int               `json:"height"`
 }
 
 // CreateProductResponse is returned after creating a product
@@ -63,20 +227,236 @@ type DeleteProductResponse struct {
 	Message string `json:"message"`
 }
 
+// ProductImageResponse is returned after uploading or updating a product image
+type ProductImageResponse struct {
+	Data ProductImagePayload `json:"data"`
+}
+
+// ProductImagesResponse is returned after reordering a product's images
+type ProductImagesResponse struct {
+	Data []ProductImagePayload `json:"data"`
+}
+
+// DeleteProductImageResponse is a simple message for image deletion success
+type DeleteProductImageResponse struct {
+	Message string `json:"message"`
+}
+
+// ------------------ Pricing Response ------------------ //
+
+type CustomerGroupPayload struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CustomerGroupResponse struct {
+	Data CustomerGroupPayload `json:"data"`
+}
+
+type GetCustomerGroupsResponse struct {
+	Data []CustomerGroupPayload `json:"data"`
+}
+
+// DeleteCustomerGroupResponse is a simple message for customer group deletion success
+type DeleteCustomerGroupResponse struct {
+	Message string `json:"message"`
+}
+
+type PriceTierPayload struct {
+	ID              uint    `json:"id"`
+	ProductID       uint    `json:"product_id"`
+	CustomerGroupID *uint   `json:"customer_group_id"` // null applies to every customer
+	MinQuantity     int     `json:"min_quantity"`
+	Price           float64 `json:"price"`
+}
+
+type PriceTierResponse struct {
+	Data PriceTierPayload `json:"data"`
+}
+
+type PriceTiersResponse struct {
+	Data []PriceTierPayload `json:"data"`
+}
+
+// DeletePriceTierResponse is a simple message for price tier deletion success
+type DeletePriceTierResponse struct {
+	Message string `json:"message"`
+}
+
+type PriceHistoryPayload struct {
+	ID        uint      `json:"id"`
+	Kind      string    `json:"kind"`
+	OldPrice  *float64  `json:"old_price"`
+	NewPrice  *float64  `json:"new_price"`
+	Source    string    `json:"source"` // admin, import or schedule
+	ChangedBy *uint     `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type PriceHistoryResponse struct {
+	Data       []PriceHistoryPayload `json:"data"`
+	Pagination PaginationPayload     `json:"pagination"`
+}
+
+type ScheduledPricePayload struct {
+	ID        uint       `json:"id"`
+	ProductID uint       `json:"product_id"`
+	Kind      string     `json:"kind"`
+	Price     float64    `json:"price"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+	Status    string     `json:"status"`
+	CreatedBy uint       `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ScheduledPriceResponse struct {
+	Data ScheduledPricePayload `json:"data"`
+}
+
+type ScheduledPricesResponse struct {
+	Data []ScheduledPricePayload `json:"data"`
+}
+
+// ------------------ Review Response ------------------ //
+
+type ReviewPayload struct {
+	ID        uint      `json:"id"`
+	ProductID uint      `json:"product_id"`
+	UserID    uint      `json:"user_id"`
+	Rating    int       `json:"rating"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReviewResponse is returned after creating or moderating a review
+type ReviewResponse struct {
+	Data ReviewPayload `json:"data"`
+}
+
+// ProductReviewsResponse is returned when listing a product's approved reviews
+type ProductReviewsResponse struct {
+	Data          []ReviewPayload   `json:"data"`
+	RatingAverage float64           `json:"rating_average"`
+	RatingCount   int               `json:"rating_count"`
+	Pagination    PaginationPayload `json:"pagination"`
+}
+
+// AdminReviewsResponse is returned when an admin lists reviews for moderation
+type AdminReviewsResponse struct {
+	Data       []ReviewPayload   `json:"data"`
+	Pagination PaginationPayload `json:"pagination"`
+}
+
+// DeleteReviewResponse is a simple message for review deletion success
+type DeleteReviewResponse struct {
+	Message string `json:"message"`
+}
+
+// ------------------ Wishlist Response ------------------ //
+
+type WishlistItemPayload struct {
+	ID        uint      `json:"id"`
+	ProductID uint      `json:"product_id"`
+	Name      string    `json:"name"`
+	Price     float64   `json:"price"`
+	Quantity  int       `json:"quantity"`
+	Available bool      `json:"available"` // false once the product is archived
+	AddedAt   time.Time `json:"added_at"`
+}
+
+type WishlistPayload struct {
+	ID         uint                  `json:"id"`
+	Name       string                `json:"name"`
+	ShareToken *string               `json:"share_token"`
+	Items      []WishlistItemPayload `json:"items"`
+	CreatedAt  time.Time             `json:"created_at"`
+	UpdatedAt  time.Time             `json:"updated_at"`
+}
+
+// SharedWishlistPayload is the read-only view of a wishlist opened through its share token
+type SharedWishlistPayload struct {
+	Name  string                `json:"name"`
+	Items []WishlistItemPayload `json:"items"`
+}
+
+type WishlistResponse struct {
+	Data WishlistPayload `json:"data"`
+}
+
+type GetWishlistsResponse struct {
+	Data []WishlistPayload `json:"data"`
+}
+
+type SharedWishlistResponse struct {
+	Data SharedWishlistPayload `json:"data"`
+}
+
+// DeleteWishlistResponse is a simple message for wishlist deletion success
+type DeleteWishlistResponse struct {
+	Message string `json:"message"`
+}
+
+// ------------------ Product Import Response ------------------ //
+
+type ImportRowErrorPayload struct {
+	Row   int    `json:"row"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+type ImportJobPayload struct {
+	ID            uint                    `json:"id"`
+	Filename      string                  `json:"filename"`
+	Format        string                  `json:"format"`
+	DryRun        bool                    `json:"dry_run"`
+	Status        string                  `json:"status"`
+	TotalRows     int                     `json:"total_rows"`
+	ProcessedRows int                     `json:"processed_rows"`
+	CreatedCount  int                     `json:"created"`
+	UpdatedCount  int                     `json:"updated"`
+	FailedCount   int                     `json:"failed"`
+	Errors        []ImportRowErrorPayload `json:"errors"`
+	Message       string                  `json:"message,omitempty"`
+	StartedAt     *time.Time              `json:"started_at"`
+	FinishedAt    *time.Time              `json:"finished_at"`
+	CreatedAt     time.Time               `json:"created_at"`
+}
+
+// ImportJobResponse is returned when starting or polling a product import
+type ImportJobResponse struct {
+	Data ImportJobPayload `json:"data"`
+}
+
 // ------------------ Order Response ------------------ //
 
 type OrderItemPayload struct {
-	ProductID   uint    `json:"product_id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Quantity    int     `json:"quantity"`
-	Price       float64 `json:"price"`
+	ProductID   uint              `json:"product_id"`
+	SKU         string            `json:"sku"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Attributes  map[string]string `json:"attributes"`
+	Quantity    int               `json:"quantity"`
+	Price       float64           `json:"price"`
+}
+
+type OrderStatusChangePayload struct {
+	Status    string    `json:"status"`
+	ChangedBy uint      `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
 }
 
 type OrderPayload struct {
 	ID        uint               `json:"id"`
 	UserID    uint               `json:"user_id"`
 	Status    string             `json:"status"`
+	Total     float64            `json:"total"`
+	Version   uint               `json:"version"`
 	Products  []OrderItemPayload `json:"products"`
 	CreatedAt time.Time          `json:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at"`
@@ -92,6 +472,17 @@ type GetOrdersResponse struct {
 	Data []OrderPayload `json:"data"`
 }
 
+// OrderDetailPayload is a single order together with its status timeline
+type OrderDetailPayload struct {
+	OrderPayload
+	History []OrderStatusChangePayload `json:"history"`
+}
+
+// OrderDetailResponse is returned when a user fetches one of their orders
+type OrderDetailResponse struct {
+	Data OrderDetailPayload `json:"data"`
+}
+
 // CancelOrderResponse is returned after cancelling an order
 type CancelOrderResponse struct {
 	Message string `json:"message"`
@@ -101,3 +492,21 @@ type CancelOrderResponse struct {
 type UpdateOrderStatusResponse struct {
 	Data OrderPayload `json:"data"`
 }
+
+// ------------------ Admin Order Response ------------------ //
+
+type AdminOrderDetailPayload struct {
+	OrderDetailPayload
+	Customer UserPayload `json:"customer"`
+}
+
+// AdminGetOrdersResponse is returned when an admin lists orders across all users
+type AdminGetOrdersResponse struct {
+	Data       []OrderPayload    `json:"data"`
+	Pagination PaginationPayload `json:"pagination"`
+}
+
+// AdminOrderDetailResponse is returned when an admin fetches a single order
+type AdminOrderDetailResponse struct {
+	Data AdminOrderDetailPayload `json:"data"`
+}
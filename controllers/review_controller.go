@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// reviewSorts maps the "sort" query param of review listings to ORDER BY clauses
+var reviewSorts = map[string]string{
+	"newest":  "created_at DESC, id DESC",
+	"oldest":  "created_at ASC, id ASC",
+	"highest": "rating DESC, created_at DESC",
+	"lowest":  "rating ASC, created_at DESC",
+}
+
+// CreateReview godoc
+// @Summary      Review a product
+// @Description  Adds the authenticated user's review of a product. Only customers with a Completed order containing the product may review it, once. Reviews are shown after an admin approves them.
+// @Tags         reviews
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int                true  "Product ID"
+// @Param        body  body  CreateReviewInput  true  "Review"
+// @Success      201  {object}  ReviewResponse
+// @Failure      400,401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/products/{id}/reviews [post]
+func CreateReview(c *gin.Context) {
+	userId := c.GetUint("user_id")
+
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	var input CreateReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, productID).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	var purchases int64
+	err = config.DB.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.product_id = ?", userId, models.Completed, product.ID).
+		Count(&purchases).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create review"})
+		return
+	}
+	if purchases == 0 {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Only customers who received this product can review it"})
+		return
+	}
+
+	var existing int64
+	config.DB.Model(&models.Review{}).Where("product_id = ? AND user_id = ?", product.ID, userId).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "You have already reviewed this product"})
+		return
+	}
+
+	review := models.Review{
+		ProductID: product.ID,
+		UserID:    userId,
+		Rating:    input.Rating,
+		Title:     input.Title,
+		Body:      input.Body,
+		Status:    models.ReviewPending,
+	}
+	if err := config.DB.Create(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create review"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ReviewResponse{Data: toReviewPayload(review)})
+}
+
+// GetProductReviews godoc
+// @Summary      List a product's reviews
+// @Description  Returns the approved reviews of a product with its average rating
+// @Tags         reviews
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id         path   int     true   "Product ID"
+// @Param        sort       query  string  false  "newest (default), oldest, highest or lowest"
+// @Param        page       query  int     false  "Page number (default 1)"
+// @Param        page_size  query  int     false  "Page size (default 20, max 100)"
+// @Success      200  {object}  ProductReviewsResponse
+// @Failure      400,401,404,500 {object} ErrorResponse
+// @Router       /api/products/{id}/reviews [get]
+func GetProductReviews(c *gin.Context) {
+	productID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid ID"})
+		return
+	}
+
+	order, ok := reviewSorts[c.DefaultQuery("sort", "newest")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "sort must be one of newest, oldest, highest or lowest"})
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, productID).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	query := config.DB.Model(&models.Review{}).Where("product_id = ? AND status = ?", product.ID, models.ReviewApproved)
+	page, pageSize := paginationParams(c)
+
+	var count int64
+	var reviews []models.Review
+	if err := query.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reviews"})
+		return
+	}
+	if err := query.Order(order).Offset((page - 1) * pageSize).Limit(pageSize).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ProductReviewsResponse{
+		Data:          toReviewPayloads(reviews),
+		RatingAverage: product.RatingAverage,
+		RatingCount:   product.RatingCount,
+		Pagination: PaginationPayload{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: count,
+		},
+	})
+}
+
+// AdminGetReviews godoc
+// @Summary      List reviews for moderation
+// @Description  Returns reviews of every status, optionally filtered by status and product (admin only)
+// @Tags         reviews
+// @Security     BearerAuth
+// @Produce      json
+// @Param        status      query  string  false  "Pending, Approved or Rejected"
+// @Param        product_id  query  int     false  "Only reviews of this product"
+// @Param        sort        query  string  false  "newest (default), oldest, highest or lowest"
+// @Param        page        query  int     false  "Page number (default 1)"
+// @Param        page_size   query  int     false  "Page size (default 20, max 100)"
+// @Success      200  {object}  AdminReviewsResponse
+// @Failure      400,401,403,500 {object} ErrorResponse
+// @Router       /api/admin/reviews [get]
+func AdminGetReviews(c *gin.Context) {
+	query := config.DB.Model(&models.Review{})
+
+	if status := c.Query("status"); status != "" {
+		if !isValidReviewStatus(status) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid review status"})
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	if productID := c.Query("product_id"); productID != "" {
+		id, err := strconv.Atoi(productID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid product_id"})
+			return
+		}
+		query = query.Where("product_id = ?", id)
+	}
+
+	order, ok := reviewSorts[c.DefaultQuery("sort", "newest")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "sort must be one of newest, oldest, highest or lowest"})
+		return
+	}
+
+	page, pageSize := paginationParams(c)
+
+	var count int64
+	var reviews []models.Review
+	if err := query.Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reviews"})
+		return
+	}
+	if err := query.Order(order).Offset((page - 1) * pageSize).Limit(pageSize).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, AdminReviewsResponse{
+		Data: toReviewPayloads(reviews),
+		Pagination: PaginationPayload{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: count,
+		},
+	})
+}
+
+// ModerateReview godoc
+// @Summary      Approve or reject a review
+// @Description  Sets a review's moderation status and updates the product's rating (admin only)
+// @Tags         reviews
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id      path   int     true  "Review ID"
+// @Param        status  query  string  true  "New Status (Pending|Approved|Rejected)"
+// @Success      200  {object}  ReviewResponse
+// @Failure      400,401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/reviews/{id}/status [put]
+func ModerateReview(c *gin.Context) {
+	newStatus := c.Query("status")
+	if !isValidReviewStatus(newStatus) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid review status"})
+		return
+	}
+
+	var review models.Review
+	if err := config.DB.First(&review, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Review not found"})
+		return
+	}
+
+	adminID := c.GetUint("user_id")
+	now := time.Now()
+	review.Status = models.ReviewStatus(newStatus)
+	review.ModeratedBy = &adminID
+	review.ModeratedAt = &now
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&review).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, review.ProductID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ReviewResponse{Data: toReviewPayload(review)})
+}
+
+// DeleteReview godoc
+// @Summary      Delete a review
+// @Description  Removes a review and updates the product's rating (admin only)
+// @Tags         reviews
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "Review ID"
+// @Success      200  {object}  DeleteReviewResponse
+// @Failure      401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/reviews/{id} [delete]
+func DeleteReview(c *gin.Context) {
+	var review models.Review
+	if err := config.DB.First(&review, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Review not found"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, review.ProductID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, DeleteReviewResponse{Message: "Review deleted"})
+}
+
+// refreshProductRating recomputes a product's average rating and count from its approved reviews.
+// It doesn't touch the product's version or updated_at, since ratings aren't an edit of the product.
+func refreshProductRating(tx *gorm.DB, productID uint) error {
+	var stats struct {
+		Average float64
+		Count   int
+	}
+	err := tx.Model(&models.Review{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, models.ReviewApproved).
+		Scan(&stats).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Model(&models.Product{}).Where("id = ?", productID).UpdateColumns(map[string]interface{}{
+		"rating_average": stats.Average,
+		"rating_count":   stats.Count,
+	}).Error
+}
+
+// isValidReviewStatus reports whether s is one of the known review statuses
+func isValidReviewStatus(s string) bool {
+	switch models.ReviewStatus(s) {
+	case models.ReviewPending, models.ReviewApproved, models.ReviewRejected:
+		return true
+	}
+	return false
+}
+
+// toReviewPayload converts a review into its response payload
+func toReviewPayload(review models.Review) ReviewPayload {
+	return ReviewPayload{
+		ID:        review.ID,
+		ProductID: review.ProductID,
+		UserID:    review.UserID,
+		Rating:    review.Rating,
+		Title:     review.Title,
+		Body:      review.Body,
+		Status:    string(review.Status),
+		CreatedAt: review.CreatedAt,
+	}
+}
+
+// toReviewPayloads converts reviews into response payloads
+func toReviewPayloads(reviews []models.Review) []ReviewPayload {
+	payloads := make([]ReviewPayload, 0, len(reviews))
+	for _, r := range reviews {
+		payloads = append(payloads, toReviewPayload(r))
+	}
+	return payloads
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/jwtkeys"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Reasons recorded on revoked sessions
+const (
+	revokedLogout         = "logout"
+	revokedRefreshReuse   = "refresh token reuse"
+	revokedPasswordReset  = "password reset"
+	revokedPasswordChange = "password change"
+)
+
+// envDuration reads a duration such as "15m" from the environment, falling back to def when unset or invalid
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", name, v, def)
+		return def
+	}
+	return d
+}
+
+// startSession opens a new session for user and returns its first token pair
+func startSession(tx *gorm.DB, c *gin.Context, user models.User) (LoginResponse, error) {
+	session := models.Session{
+		UserID:     user.ID,
+		UserAgent:  c.Request.UserAgent(),
+		IP:         c.ClientIP(),
+		ExpiresAt:  time.Now().Add(envDuration("REFRESH_TOKEN_TTL", defaultRefreshTokenTTL)),
+		LastUsedAt: time.Now(),
+	}
+	if err := tx.Create(&session).Error; err != nil {
+		return LoginResponse{}, err
+	}
+	return issueTokens(tx, session, user)
+}
+
+// issueTokens signs a new access token for the session and stores a new refresh token for it
+func issueTokens(tx *gorm.DB, session models.Session, user models.User) (LoginResponse, error) {
+	refreshToken, err := randomToken()
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	if err := tx.Create(&models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: session.ExpiresAt,
+	}).Error; err != nil {
+		return LoginResponse{}, err
+	}
+
+	jti, err := randomToken()
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	accessTTL := envDuration("ACCESS_TOKEN_TTL", defaultAccessTokenTTL)
+	tokenString, err := jwtkeys.Default.IssueAccessToken(user.ID, session.ID, jti, accessTTL)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTTL.Seconds()),
+	}, nil
+}
+
+// revokeSession marks a session as revoked so neither its access nor its refresh tokens work anymore
+func revokeSession(tx *gorm.DB, sessionID uint, reason string) error {
+	return tx.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// revokeUserSessions revokes every open session of a user, logging them out everywhere
+func revokeUserSessions(tx *gorm.DB, userID uint, reason string) error {
+	return tx.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// randomToken returns 32 random bytes, hex encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken is how opaque tokens are stored, so a database leak doesn't hand out usable tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
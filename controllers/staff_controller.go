@@ -0,0 +1,537 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/mailer"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/rbac"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const defaultStaffInvitationTTL = 72 * time.Hour
+
+var (
+	errLastSuperAdmin    = errors.New("last super-admin")
+	errInvitationUsed    = errors.New("invitation already used")
+	errUnknownPermission = errors.New("unknown permission")
+)
+
+// GetPermissions godoc
+// @Summary      List permissions
+// @Description  Returns every permission that can be given to a role (requires staff:manage)
+// @Tags         staff
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  PermissionsResponse
+// @Failure      401,403 {object} ErrorResponse
+// @Router       /api/admin/permissions [get]
+func GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, PermissionsResponse{Data: rbac.All})
+}
+
+// GetRoles godoc
+// @Summary      List roles
+// @Description  Returns the built-in and custom staff roles with their permissions (requires staff:manage)
+// @Tags         staff
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  GetRolesResponse
+// @Failure      401,403,500 {object} ErrorResponse
+// @Router       /api/admin/roles [get]
+func GetRoles(c *gin.Context) {
+	var roles []models.Role
+	if err := config.DB.Preload("Permissions").Order("built_in DESC, name ASC").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch roles"})
+		return
+	}
+
+	payloads := make([]RolePayload, 0, len(roles))
+	for _, r := range roles {
+		payloads = append(payloads, toRolePayload(r))
+	}
+
+	c.JSON(http.StatusOK, GetRolesResponse{Data: payloads})
+}
+
+// CreateRole godoc
+// @Summary      Create a role
+// @Description  Adds a custom staff role with the given permissions (requires staff:manage)
+// @Tags         staff
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  RoleInput  true  "Role"
+// @Success      201  {object}  RoleResponse
+// @Failure      400,401,403,409,500 {object} ErrorResponse
+// @Router       /api/admin/roles [post]
+func CreateRole(c *gin.Context) {
+	var input RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validatePermissions(input.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.Role{}).Where("name = ?", input.Name).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "A role with this name already exists"})
+		return
+	}
+
+	role := models.Role{Name: input.Name, Description: input.Description}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return rbac.SetPermissions(tx, role.ID, input.Permissions)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create role"})
+		return
+	}
+
+	config.DB.Preload("Permissions").First(&role, role.ID)
+	c.JSON(http.StatusCreated, RoleResponse{Data: toRolePayload(role)})
+}
+
+// UpdateRole godoc
+// @Summary      Update a role
+// @Description  Changes a custom role's name, description and permissions. Built-in roles can't be changed (requires staff:manage).
+// @Tags         staff
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int        true  "Role ID"
+// @Param        body  body  RoleInput  true  "Role"
+// @Success      200  {object}  RoleResponse
+// @Failure      400,401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/admin/roles/{id} [put]
+func UpdateRole(c *gin.Context) {
+	var role models.Role
+	if err := config.DB.First(&role, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Role not found"})
+		return
+	}
+	if role.BuiltIn {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Built-in roles can't be changed"})
+		return
+	}
+
+	var input RoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := validatePermissions(input.Permissions); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.Role{}).Where("name = ? AND id <> ?", input.Name, role.ID).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "A role with this name already exists"})
+		return
+	}
+
+	role.Name = input.Name
+	role.Description = input.Description
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&role).Error; err != nil {
+			return err
+		}
+		return rbac.SetPermissions(tx, role.ID, input.Permissions)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update role"})
+		return
+	}
+
+	config.DB.Preload("Permissions").First(&role, role.ID)
+	c.JSON(http.StatusOK, RoleResponse{Data: toRolePayload(role)})
+}
+
+// DeleteRole godoc
+// @Summary      Delete a role
+// @Description  Deletes a custom role and takes it away from everyone holding it. Built-in roles can't be deleted (requires staff:manage).
+// @Tags         staff
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "Role ID"
+// @Success      200  {object}  MessageResponse
+// @Failure      401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/admin/roles/{id} [delete]
+func DeleteRole(c *gin.Context) {
+	var role models.Role
+	if err := config.DB.First(&role, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Role not found"})
+		return
+	}
+	if role.BuiltIn {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Built-in roles can't be deleted"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM staff_invitation_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", role.ID).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Role deleted"})
+}
+
+// GetStaff godoc
+// @Summary      List staff members
+// @Description  Returns every user holding at least one role (requires staff:manage)
+// @Tags         staff
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  GetUsersResponse
+// @Failure      401,403,500 {object} ErrorResponse
+// @Router       /api/admin/staff [get]
+func GetStaff(c *gin.Context) {
+	var users []models.User
+	if err := config.DB.Preload("Roles").
+		Where("id IN (SELECT user_id FROM user_roles)").
+		Order("email ASC").
+		Find(&users).Error; err != nil {
+
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch staff"})
+		return
+	}
+
+	payloads := make([]UserPayload, 0, len(users))
+	for _, u := range users {
+		payloads = append(payloads, toUserPayload(u))
+	}
+
+	c.JSON(http.StatusOK, GetUsersResponse{Data: payloads})
+}
+
+// SetUserRoles godoc
+// @Summary      Set a user's roles
+// @Description  Replaces the roles of a user; an empty list makes them a regular customer again.
+// @Description  The last super-admin can't lose that role (requires staff:manage).
+// @Tags         staff
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int             true  "User ID"
+// @Param        body  body  UserRolesInput  true  "Role names"
+// @Success      200  {object}  UserResponse
+// @Failure      400,401,403,404,409,500 {object} ErrorResponse
+// @Router       /api/admin/users/{id}/roles [put]
+func SetUserRoles(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+
+	var input UserRolesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	roles, err := findRolesByName(input.Roles)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if hasRole(user.Roles, rbac.SuperAdmin) && !hasRole(roles, rbac.SuperAdmin) {
+			if err := ensureOtherSuperAdmin(tx, user.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&user).Association("Roles").Replace(roles)
+	})
+	if err != nil {
+		if errors.Is(err, errLastSuperAdmin) {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: "The last super-admin can't lose the super-admin role"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update roles"})
+		return
+	}
+	user.Roles = roles
+
+	c.JSON(http.StatusOK, UserResponse{Data: toUserPayload(user)})
+}
+
+// InviteStaff godoc
+// @Summary      Invite a staff member
+// @Description  Emails an invitation to create a staff account with the given roles (requires staff:manage)
+// @Tags         staff
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  StaffInvitationInput  true  "Invitation"
+// @Success      201  {object}  StaffInvitationResponse
+// @Failure      400,401,403,409,500 {object} ErrorResponse
+// @Router       /api/admin/staff/invitations [post]
+func InviteStaff(c *gin.Context) {
+	var input StaffInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	roles, err := findRolesByName(input.Roles)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.User{}).Where("email = ?", input.Email).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "A user with this email already exists; assign roles to them instead"})
+		return
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create invitation"})
+		return
+	}
+
+	ttl := envDuration("STAFF_INVITATION_TTL", defaultStaffInvitationTTL)
+	invitation := models.StaffInvitation{
+		Email:     input.Email,
+		TokenHash: hashToken(token),
+		Roles:     roles,
+		InvitedBy: c.GetUint("user_id"),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := config.DB.Omit("Roles.*").Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create invitation"})
+		return
+	}
+
+	msg := mailer.Message{
+		To:      invitation.Email,
+		Subject: "You've been invited to join the store team",
+		Body: fmt.Sprintf("You've been invited to join the store team as %s.\n\n"+
+			"Open this link within %s to set your password and activate your account:\n\n%s\n",
+			strings.Join(roleNames(roles), ", "), ttl, frontendLink("STAFF_INVITATION_URL", "/accept-invitation", token)),
+	}
+	if err := mailer.Default.Send(c.Request.Context(), msg); err != nil {
+		log.Printf("Sending staff invitation %d failed: %v", invitation.ID, err)
+		config.DB.Select("Roles").Delete(&invitation)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not send invitation email"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, StaffInvitationResponse{Data: toStaffInvitationPayload(invitation)})
+}
+
+// GetStaffInvitations godoc
+// @Summary      List pending staff invitations
+// @Description  Returns invitations that have not been accepted, revoked or expired yet (requires staff:manage)
+// @Tags         staff
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  StaffInvitationsResponse
+// @Failure      401,403,500 {object} ErrorResponse
+// @Router       /api/admin/staff/invitations [get]
+func GetStaffInvitations(c *gin.Context) {
+	var invitations []models.StaffInvitation
+	if err := config.DB.Preload("Roles").
+		Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", time.Now()).
+		Order("created_at DESC").
+		Find(&invitations).Error; err != nil {
+
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch invitations"})
+		return
+	}
+
+	payloads := make([]StaffInvitationPayload, 0, len(invitations))
+	for _, inv := range invitations {
+		payloads = append(payloads, toStaffInvitationPayload(inv))
+	}
+
+	c.JSON(http.StatusOK, StaffInvitationsResponse{Data: payloads})
+}
+
+// RevokeStaffInvitation godoc
+// @Summary      Revoke a staff invitation
+// @Description  Makes a pending invitation unusable (requires staff:manage)
+// @Tags         staff
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "Invitation ID"
+// @Success      200  {object}  MessageResponse
+// @Failure      401,403,404,500 {object} ErrorResponse
+// @Router       /api/admin/staff/invitations/{id} [delete]
+func RevokeStaffInvitation(c *gin.Context) {
+	result := config.DB.Model(&models.StaffInvitation{}).
+		Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", c.Param("id")).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to revoke invitation"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Pending invitation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Invitation revoked"})
+}
+
+// AcceptStaffInvitation godoc
+// @Summary      Accept a staff invitation
+// @Description  Creates the invited staff account with the password chosen by the invitee
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   AcceptInvitationInput  true  "Invitation token and password"
+// @Success      201   {object} RegisterResponse
+// @Failure      400,409,500 {object} ErrorResponse
+// @Router       /api/auth/invitations/accept [post]
+func AcceptStaffInvitation(c *gin.Context) {
+	var input AcceptInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var invitation models.StaffInvitation
+	err := config.DB.Preload("Roles").Where("token_hash = ?", hashToken(input.Token)).First(&invitation).Error
+	if err != nil || invitation.AcceptedAt != nil || invitation.RevokedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid or expired invitation"})
+		return
+	}
+
+	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Unable to hash password"})
+		return
+	}
+
+	// The invitation reached this address, which proves the user owns it
+	now := time.Now()
+	user := models.User{
+		Email:           invitation.Email,
+		Password:        string(hashedPwd),
+		Roles:           invitation.Roles,
+		EmailVerified:   true,
+		EmailVerifiedAt: &now,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&invitation).Where("accepted_at IS NULL").Update("accepted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvitationUsed
+		}
+		return tx.Omit("Roles.*").Create(&user).Error
+	})
+	if err != nil {
+		if errors.Is(err, errInvitationUsed) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid or expired invitation"})
+			return
+		}
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "User already exists"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, RegisterResponse{
+		Message: "Staff account created",
+		User:    toUserPayload(user),
+	})
+}
+
+// validatePermissions makes sure every permission is known
+func validatePermissions(permissions []string) error {
+	for _, p := range permissions {
+		if !rbac.IsValid(p) {
+			return fmt.Errorf("%w %q", errUnknownPermission, p)
+		}
+	}
+	return nil
+}
+
+// findRolesByName loads the named roles, failing if any of them doesn't exist
+func findRolesByName(names []string) ([]models.Role, error) {
+	roles := []models.Role{}
+	if len(names) == 0 {
+		return roles, nil
+	}
+	if err := config.DB.Where("name IN ?", names).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if !hasRole(roles, name) {
+			return nil, fmt.Errorf("unknown role %q", name)
+		}
+	}
+	return roles, nil
+}
+
+// hasRole reports whether roles contains the role called name
+func hasRole(roles []models.Role, name string) bool {
+	for _, r := range roles {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// toRolePayload converts a role into its response payload
+func toRolePayload(role models.Role) RolePayload {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		permissions = append(permissions, p.Permission)
+	}
+	return RolePayload{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		BuiltIn:     role.BuiltIn,
+		Permissions: permissions,
+	}
+}
+
+// toStaffInvitationPayload converts a staff invitation into its response payload
+func toStaffInvitationPayload(invitation models.StaffInvitation) StaffInvitationPayload {
+	return StaffInvitationPayload{
+		ID:        invitation.ID,
+		Email:     invitation.Email,
+		Roles:     roleNames(invitation.Roles),
+		InvitedBy: invitation.InvitedBy,
+		ExpiresAt: invitation.ExpiresAt,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
@@ -0,0 +1,446 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/totp"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTwoFactorChallengeTTL = 5 * time.Minute
+	// maxTwoFactorAttempts is how many codes can be tried against one login challenge
+	maxTwoFactorAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+// errChallengeUsed is returned when a login challenge was used concurrently
+var errChallengeUsed = errors.New("two-factor challenge already used")
+
+type TwoFactorSetupInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+}
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Code            string `json:"code" binding:"required"` // authenticator or recovery code
+}
+
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // authenticator or recovery code
+}
+
+// SetupTwoFactor godoc
+// @Summary      Start two-factor authentication setup
+// @Description  Creates a new authenticator secret for the current user. Show the otpauth URI as a QR code, then confirm
+// @Description  with a code from the app through /api/me/2fa/enable. Until then, login works as before.
+// @Tags         account
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  TwoFactorSetupInput  true  "Current password"
+// @Success      200  {object}  TwoFactorSetupResponse
+// @Failure      400,401,404,409,429,500 {object} ErrorResponse
+// @Router       /api/me/2fa/setup [post]
+func SetupTwoFactor(c *gin.Context) {
+	var input TwoFactorSetupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Two-factor authentication is already enabled"})
+		return
+	}
+	// A stolen access token alone must not be enough to tie the account to someone else's authenticator
+	if !checkCurrentPassword(c, user, input.CurrentPassword) {
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not start two-factor setup"})
+		return
+	}
+	if err := config.DB.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not start two-factor setup"})
+		return
+	}
+
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "E-commerce API"
+	}
+
+	c.JSON(http.StatusOK, TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    totp.URI(issuer, user.Email, secret),
+	})
+}
+
+// EnableTwoFactor godoc
+// @Summary      Enable two-factor authentication
+// @Description  Confirms the setup with a code from the authenticator app and turns two-factor authentication on.
+// @Description  Returns recovery codes for when the device is lost; they are only shown this once.
+// @Tags         account
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  TwoFactorCodeInput  true  "Code from the authenticator app"
+// @Success      200  {object}  RecoveryCodesResponse
+// @Failure      400,401,404,409,500 {object} ErrorResponse
+// @Router       /api/me/2fa/enable [post]
+func EnableTwoFactor(c *gin.Context) {
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+	if user.TOTPEnabledAt != nil {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Two-factor authentication is already enabled"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Start two-factor setup first"})
+		return
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, input.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid code"})
+		return
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"totp_enabled_at": time.Now(),
+			"totp_last_step":  step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not enable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor godoc
+// @Summary      Disable two-factor authentication
+// @Description  Turns two-factor authentication off after checking the password and a current code.
+// @Description  Not allowed for staff while REQUIRE_STAFF_2FA is on.
+// @Tags         account
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  DisableTwoFactorInput  true  "Current password and code"
+// @Success      200  {object}  MessageResponse
+// @Failure      400,401,403,404,429,500 {object} ErrorResponse
+// @Router       /api/me/2fa/disable [post]
+func DisableTwoFactor(c *gin.Context) {
+	var input DisableTwoFactorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Preload("Roles").First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Two-factor authentication is not enabled"})
+		return
+	}
+	if len(user.Roles) > 0 && staffTwoFactorRequired() {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Staff accounts must keep two-factor authentication enabled"})
+		return
+	}
+
+	if !checkCurrentPassword(c, user, input.CurrentPassword) {
+		return
+	}
+	ok, err := checkSecondFactor(config.DB, user, input.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not check code"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid code"})
+		return
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error { return clearTwoFactor(tx, user.ID) }); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not disable two-factor authentication"})
+		return
+	}
+
+	c.JSON(http.StatusOK, MessageResponse{Message: "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Replace recovery codes
+// @Description  Creates a new set of recovery codes after checking a current code; the old ones stop working
+// @Tags         account
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  TwoFactorCodeInput  true  "Authenticator or recovery code"
+// @Success      200  {object}  RecoveryCodesResponse
+// @Failure      400,401,404,500 {object} ErrorResponse
+// @Router       /api/me/2fa/recovery-codes [post]
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, c.GetUint("user_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "User not found"})
+		return
+	}
+	if user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Two-factor authentication is not enabled"})
+		return
+	}
+
+	ok, err := checkSecondFactor(config.DB, user, input.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not check code"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid code"})
+		return
+	}
+
+	var codes []string
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// LoginTwoFactor godoc
+// @Summary      Finish a two-factor login
+// @Description  Exchanges the challenge token returned by /api/auth/login and a code from the authenticator app,
+// @Description  or a recovery code, for an access and refresh token. A challenge allows a few wrong codes before it stops working.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   TwoFactorLoginInput  true  "Challenge token and code"
+// @Success      200   {object} LoginResponse
+// @Failure      400,401,403,429,500 {object} ErrorResponse
+// @Router       /api/auth/login/2fa [post]
+func LoginTwoFactor(c *gin.Context) {
+	var input TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var challenge models.TwoFactorChallenge
+	err := config.DB.Where("token_hash = ?", hashToken(input.ChallengeToken)).First(&challenge).Error
+	if err != nil || challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid or expired login challenge, please log in again"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, challenge.UserID).Error; err != nil || user.TOTPEnabledAt == nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid or expired login challenge, please log in again"})
+		return
+	}
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "Account is disabled"})
+		return
+	}
+
+	// Wrong codes count towards the account's lockout, so new challenges don't buy more guesses
+	if !reserveLogin(c, user.Email, &user.ID) {
+		return
+	}
+
+	// Take one of the challenge's attempts before checking the code, so concurrent requests can't
+	// try more codes than it allows
+	result := config.DB.Model(&challenge).
+		Where("attempts < ? AND used_at IS NULL", maxTwoFactorAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		refundLogin(c, user.Email)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not check code"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		refundLogin(c, user.Email)
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid or expired login challenge, please log in again"})
+		return
+	}
+
+	ok, err := checkSecondFactor(config.DB, user, input.Code)
+	if err != nil {
+		refundLogin(c, user.Email)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not check code"})
+		return
+	}
+	if !ok {
+		auditLoginFailure(c, user.Email, &user.ID, loginFailedTwoFactor)
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid code"})
+		return
+	}
+
+	var tokens LoginResponse
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&challenge).Where("used_at IS NULL").Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errChallengeUsed
+		}
+		var err error
+		tokens, err = startSession(tx, c, user)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errChallengeUsed) {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid or expired login challenge, please log in again"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create token"})
+		return
+	}
+
+	loginSucceeded(c, user.Email)
+	c.JSON(http.StatusOK, tokens)
+}
+
+// startTwoFactorChallenge stores a login challenge for user, whose password was just checked
+func startTwoFactorChallenge(user models.User) (TwoFactorChallengeResponse, error) {
+	token, err := randomToken()
+	if err != nil {
+		return TwoFactorChallengeResponse{}, err
+	}
+
+	ttl := envDuration("TWO_FACTOR_CHALLENGE_TTL", defaultTwoFactorChallengeTTL)
+	if err := config.DB.Create(&models.TwoFactorChallenge{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}).Error; err != nil {
+		return TwoFactorChallengeResponse{}, err
+	}
+
+	return TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int(ttl.Seconds()),
+	}, nil
+}
+
+// checkSecondFactor accepts a current authenticator code or an unused recovery code of user.
+// Either works only once: authenticator codes can't be replayed and recovery codes are used up.
+func checkSecondFactor(db *gorm.DB, user models.User, code string) (bool, error) {
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		result := db.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		return result.RowsAffected > 0, result.Error
+	}
+
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// replaceRecoveryCodes deletes the recovery codes of a user and returns a new set
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		codes = append(codes, raw[:4]+"-"+raw[4:])
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: hashToken(raw)})
+	}
+
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// clearTwoFactor turns two-factor authentication off for a user and removes their recovery codes
+func clearTwoFactor(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+// normalizeRecoveryCode accepts recovery codes typed with or without the dash and in any case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// staffTwoFactorRequired reports whether REQUIRE_STAFF_2FA makes two-factor authentication mandatory for staff
+func staffTwoFactorRequired() bool {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_STAFF_2FA"))
+	return required
+}
@@ -0,0 +1,454 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateWishlist godoc
+// @Summary      Create a wishlist
+// @Description  Creates a new, empty named wishlist for the authenticated user
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body  WishlistInput  true  "Wishlist"
+// @Success      201  {object}  WishlistResponse
+// @Failure      400,401,500 {object} ErrorResponse
+// @Router       /api/wishlists [post]
+func CreateWishlist(c *gin.Context) {
+	userId := c.GetUint("user_id")
+
+	var input WishlistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	wishlist := models.Wishlist{UserID: userId, Name: input.Name}
+	if err := config.DB.Create(&wishlist).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, WishlistResponse{Data: toWishlistPayload(wishlist)})
+}
+
+// GetWishlists godoc
+// @Summary      List the authenticated user's wishlists
+// @Description  Returns all wishlists of the logged-in user with their items
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  GetWishlistsResponse
+// @Failure      401,500 {object} ErrorResponse
+// @Router       /api/wishlists [get]
+func GetWishlists(c *gin.Context) {
+	userId := c.GetUint("user_id")
+
+	var wishlists []models.Wishlist
+	if err := config.DB.Preload("Items", wishlistItemsByDate).
+		Preload("Items.Product", withArchivedProducts).
+		Where("user_id = ?", userId).
+		Order("created_at ASC").
+		Find(&wishlists).Error; err != nil {
+
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch wishlists"})
+		return
+	}
+
+	payloads := make([]WishlistPayload, 0, len(wishlists))
+	for _, w := range wishlists {
+		payloads = append(payloads, toWishlistPayload(w))
+	}
+
+	c.JSON(http.StatusOK, GetWishlistsResponse{Data: payloads})
+}
+
+// GetWishlistByID godoc
+// @Summary      Get one of the authenticated user's wishlists
+// @Description  Returns a single wishlist belonging to the logged-in user, with its items
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "Wishlist ID"
+// @Success      200  {object}  WishlistResponse
+// @Failure      401,404 {object} ErrorResponse
+// @Router       /api/wishlists/{id} [get]
+func GetWishlistByID(c *gin.Context) {
+	wishlist, ok := findUserWishlist(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, WishlistResponse{Data: toWishlistPayload(wishlist)})
+}
+
+// UpdateWishlist godoc
+// @Summary      Rename a wishlist
+// @Description  Changes the name of one of the logged-in user's wishlists
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int            true  "Wishlist ID"
+// @Param        body  body  WishlistInput  true  "Wishlist"
+// @Success      200  {object}  WishlistResponse
+// @Failure      400,401,404,500 {object} ErrorResponse
+// @Router       /api/wishlists/{id} [put]
+func UpdateWishlist(c *gin.Context) {
+	wishlist, ok := findUserWishlist(c)
+	if !ok {
+		return
+	}
+
+	var input WishlistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := config.DB.Model(&wishlist).Update("name", input.Name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WishlistResponse{Data: toWishlistPayload(wishlist)})
+}
+
+// DeleteWishlist godoc
+// @Summary      Delete a wishlist
+// @Description  Deletes one of the logged-in user's wishlists and its items
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "Wishlist ID"
+// @Success      200  {object}  DeleteWishlistResponse
+// @Failure      401,404,500 {object} ErrorResponse
+// @Router       /api/wishlists/{id} [delete]
+func DeleteWishlist(c *gin.Context) {
+	wishlist, ok := findUserWishlist(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("wishlist_id = ?", wishlist.ID).Delete(&models.WishlistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&wishlist).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to delete wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, DeleteWishlistResponse{Message: "Wishlist deleted"})
+}
+
+// AddWishlistItem godoc
+// @Summary      Add a product to a wishlist
+// @Description  Saves a product to one of the logged-in user's wishlists. Adding a product that is already there updates its quantity.
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  int                true  "Wishlist ID"
+// @Param        body  body  WishlistItemInput  true  "Product to save"
+// @Success      200  {object}  WishlistResponse
+// @Failure      400,401,404,500 {object} ErrorResponse
+// @Router       /api/wishlists/{id}/items [post]
+func AddWishlistItem(c *gin.Context) {
+	wishlist, ok := findUserWishlist(c)
+	if !ok {
+		return
+	}
+
+	var input WishlistItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	if input.Quantity == 0 {
+		input.Quantity = 1
+	}
+
+	var product models.Product
+	if err := config.DB.First(&product, input.ProductID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Product not found"})
+		return
+	}
+
+	var item models.WishlistItem
+	err := config.DB.Where("wishlist_id = ? AND product_id = ?", wishlist.ID, product.ID).First(&item).Error
+	switch {
+	case err == nil:
+		err = config.DB.Model(&item).Update("quantity", input.Quantity).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		item = models.WishlistItem{WishlistID: wishlist.ID, ProductID: product.ID, Quantity: input.Quantity}
+		err = config.DB.Create(&item).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to add product to wishlist"})
+		return
+	}
+
+	if wishlist, ok = findUserWishlist(c); !ok {
+		return
+	}
+	c.JSON(http.StatusOK, WishlistResponse{Data: toWishlistPayload(wishlist)})
+}
+
+// RemoveWishlistItem godoc
+// @Summary      Remove a product from a wishlist
+// @Description  Removes an item from one of the logged-in user's wishlists
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id       path  int  true  "Wishlist ID"
+// @Param        item_id  path  int  true  "Wishlist item ID"
+// @Success      200  {object}  WishlistResponse
+// @Failure      401,404,500 {object} ErrorResponse
+// @Router       /api/wishlists/{id}/items/{item_id} [delete]
+func RemoveWishlistItem(c *gin.Context) {
+	wishlist, ok := findUserWishlist(c)
+	if !ok {
+		return
+	}
+
+	result := config.DB.Where("id = ? AND wishlist_id = ?", c.Param("item_id"), wishlist.ID).Delete(&models.WishlistItem{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to remove product from wishlist"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Wishlist item not found"})
+		return
+	}
+
+	if wishlist, ok = findUserWishlist(c); !ok {
+		return
+	}
+	c.JSON(http.StatusOK, WishlistResponse{Data: toWishlistPayload(wishlist)})
+}
+
+// ShareWishlist godoc
+// @Summary      Share a wishlist
+// @Description  Generates a read-only share token for one of the logged-in user's wishlists. Sharing again replaces the token, so old links stop working.
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "Wishlist ID"
+// @Success      200  {object}  WishlistResponse
+// @Failure      401,404,500 {object} ErrorResponse
+// @Router       /api/wishlists/{id}/share [post]
+func ShareWishlist(c *gin.Context) {
+	wishlist, ok := findUserWishlist(c)
+	if !ok {
+		return
+	}
+
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to share wishlist"})
+		return
+	}
+	token := hex.EncodeToString(random)
+
+	if err := config.DB.Model(&wishlist).Update("share_token", token).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to share wishlist"})
+		return
+	}
+	wishlist.ShareToken = &token
+
+	c.JSON(http.StatusOK, WishlistResponse{Data: toWishlistPayload(wishlist)})
+}
+
+// UnshareWishlist godoc
+// @Summary      Stop sharing a wishlist
+// @Description  Revokes the share token of one of the logged-in user's wishlists
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path  int  true  "Wishlist ID"
+// @Success      200  {object}  WishlistResponse
+// @Failure      401,404,500 {object} ErrorResponse
+// @Router       /api/wishlists/{id}/share [delete]
+func UnshareWishlist(c *gin.Context) {
+	wishlist, ok := findUserWishlist(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Model(&wishlist).Update("share_token", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to stop sharing wishlist"})
+		return
+	}
+	wishlist.ShareToken = nil
+
+	c.JSON(http.StatusOK, WishlistResponse{Data: toWishlistPayload(wishlist)})
+}
+
+// GetSharedWishlist godoc
+// @Summary      View a shared wishlist
+// @Description  Returns a wishlist by its share token. No authentication is needed.
+// @Tags         wishlists
+// @Produce      json
+// @Param        token  path  string  true  "Share token"
+// @Success      200  {object}  SharedWishlistResponse
+// @Failure      404 {object} ErrorResponse
+// @Router       /api/shared-wishlists/{token} [get]
+func GetSharedWishlist(c *gin.Context) {
+	var wishlist models.Wishlist
+	if err := config.DB.Preload("Items", wishlistItemsByDate).
+		Preload("Items.Product", withArchivedProducts).
+		Where("share_token = ?", c.Param("token")).
+		First(&wishlist).Error; err != nil {
+
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Wishlist not found"})
+		return
+	}
+
+	payload := toWishlistPayload(wishlist)
+	c.JSON(http.StatusOK, SharedWishlistResponse{Data: SharedWishlistPayload{
+		Name:  payload.Name,
+		Items: payload.Items,
+	}})
+}
+
+// OrderWishlist godoc
+// @Summary      Order items from a wishlist
+// @Description  Places an order for the selected wishlist items (all of them when item_ids is empty) and removes them from the wishlist.
+// @Description  Retries sent with the same Idempotency-Key replay the original response.
+// @Tags         wishlists
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id               path    int                 true   "Wishlist ID"
+// @Param        Idempotency-Key  header  string              false  "Unique key identifying this order attempt"
+// @Param        body             body    OrderWishlistInput  false  "Items to order"
+// @Success      201  {object}  CreateOrderResponse
+// @Failure      400,401,404,409,422,500 {object} ErrorResponse
+// @Router       /api/wishlists/{id}/order [post]
+func OrderWishlist(c *gin.Context) {
+	userId := c.GetUint("user_id")
+
+	wishlist, ok := findUserWishlist(c)
+	if !ok {
+		return
+	}
+
+	var input OrderWishlistInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	selected := map[uint]bool{}
+	for _, id := range input.ItemIDs {
+		selected[id] = true
+	}
+
+	var items []models.WishlistItem
+	for _, item := range wishlist.Items {
+		if len(selected) == 0 || selected[item.ID] {
+			items = append(items, item)
+			delete(selected, item.ID)
+		}
+	}
+	if len(selected) > 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wishlist item not found"})
+		return
+	}
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wishlist is empty"})
+		return
+	}
+
+	lines := make([]orderLine, 0, len(items))
+	itemIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		if item.Product.DeletedAt.Valid {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Wishlist contains products that are no longer available"})
+			return
+		}
+		lines = append(lines, orderLine{ProductID: item.ProductID, Quantity: item.Quantity})
+		itemIDs = append(itemIDs, item.ID)
+	}
+
+	order, err := placeOrder(userId, lines)
+	if err != nil {
+		if errors.Is(err, errOrderProductNotFound) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Product not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Could not create order"})
+		return
+	}
+
+	// The order is already placed, so a failure here only leaves the items on the list
+	config.DB.Where("id IN ?", itemIDs).Delete(&models.WishlistItem{})
+
+	c.JSON(http.StatusCreated, CreateOrderResponse{
+		Data: toOrderPayload(order),
+	})
+}
+
+// findUserWishlist loads the wishlist in the :id param if it belongs to the authenticated user,
+// writing a 404 response and returning false otherwise
+func findUserWishlist(c *gin.Context) (models.Wishlist, bool) {
+	var wishlist models.Wishlist
+	if err := config.DB.Preload("Items", wishlistItemsByDate).
+		Preload("Items.Product", withArchivedProducts).
+		Where("id = ? AND user_id = ?", c.Param("id"), c.GetUint("user_id")).
+		First(&wishlist).Error; err != nil {
+
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Wishlist not found"})
+		return models.Wishlist{}, false
+	}
+	return wishlist, true
+}
+
+// wishlistItemsByDate orders preloaded wishlist items oldest first
+func wishlistItemsByDate(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at ASC, id ASC")
+}
+
+// withArchivedProducts lets preloads resolve archived products so they can be shown as unavailable
+func withArchivedProducts(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// toWishlistPayload converts a wishlist into its response payload
+func toWishlistPayload(wishlist models.Wishlist) WishlistPayload {
+	items := make([]WishlistItemPayload, 0, len(wishlist.Items))
+	for _, item := range wishlist.Items {
+		items = append(items, WishlistItemPayload{
+			ID:        item.ID,
+			ProductID: item.ProductID,
+			Name:      item.Product.Name,
+			Price:     item.Product.Price,
+			Quantity:  item.Quantity,
+			Available: !item.Product.DeletedAt.Valid,
+			AddedAt:   item.CreatedAt,
+		})
+	}
+
+	return WishlistPayload{
+		ID:         wishlist.ID,
+		Name:       wishlist.Name,
+		ShareToken: wishlist.ShareToken,
+		Items:      items,
+		CreatedAt:  wishlist.CreatedAt,
+		UpdatedAt:  wishlist.UpdatedAt,
+	}
+}
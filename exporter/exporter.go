@@ -0,0 +1,205 @@
+// Package exporter writes products out as CSV, JSON Lines or XLSX, one row at a time,
+// so a whole catalog can be streamed without holding it in memory.
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/models"
+)
+
+type Format string
+
+const (
+	CSV       Format = "csv"
+	JSONLines Format = "jsonl"
+	XLSX      Format = "xlsx"
+)
+
+// Columns lists every exportable product column, in their default order
+var Columns = []string{
+	"id", "sku", "gtin", "name", "description", "price", "attributes",
+	"version", "created_at", "updated_at", "archived_at",
+}
+
+// ParseFormat validates a format name given by the user
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case CSV:
+		return CSV, nil
+	case JSONLines, "jsonlines", "ndjson":
+		return JSONLines, nil
+	case XLSX:
+		return XLSX, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q (expected csv, jsonl or xlsx)", s)
+}
+
+// ContentType is the MIME type of an export in the given format
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case JSONLines:
+		return "application/x-ndjson"
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// ParseColumns turns a comma-separated column list into column names, or all columns if s is empty
+func ParseColumns(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return Columns, nil
+	}
+
+	known := make(map[string]bool, len(Columns))
+	for _, c := range Columns {
+		known[c] = true
+	}
+
+	var columns []string
+	seen := map[string]bool{}
+	for _, c := range strings.Split(s, ",") {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if !known[c] {
+			return nil, fmt.Errorf("unknown column %q", c)
+		}
+		if !seen[c] {
+			seen[c] = true
+			columns = append(columns, c)
+		}
+	}
+	return columns, nil
+}
+
+// Writer writes products as rows of the selected columns
+type Writer interface {
+	Write(product models.Product) error
+	// Close finishes the file; it does not close the underlying io.Writer
+	Close() error
+}
+
+// NewWriter starts an export of the given columns to w. For CSV and XLSX the header row is written immediately.
+func NewWriter(w io.Writer, format Format, columns []string) (Writer, error) {
+	switch format {
+	case CSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{w: cw, columns: columns}, nil
+	case JSONLines:
+		return &jsonLinesWriter{enc: json.NewEncoder(w), columns: columns}, nil
+	case XLSX:
+		return newXLSXWriter(w, columns)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// value returns a product's value for a column, typed for formats that care (JSON, XLSX)
+func value(p models.Product, column string) interface{} {
+	switch column {
+	case "id":
+		return p.ID
+	case "sku":
+		return p.SKU
+	case "gtin":
+		return p.GTIN
+	case "name":
+		return p.Name
+	case "description":
+		return p.Description
+	case "price":
+		return p.Price
+	case "attributes":
+		if p.Attributes == nil {
+			return map[string]string{}
+		}
+		return map[string]string(p.Attributes)
+	case "version":
+		return p.Version
+	case "created_at":
+		return p.CreatedAt
+	case "updated_at":
+		return p.UpdatedAt
+	case "archived_at":
+		if p.DeletedAt.Valid {
+			return p.DeletedAt.Time
+		}
+		return nil
+	}
+	return nil
+}
+
+// text formats a column value for text-only formats (CSV, XLSX strings)
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case map[string]string:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// cellText is text for a cell of a spreadsheet format. Strings that spreadsheet programs would read as
+// a formula are prefixed with a quote, so product data can't inject formulas into an export.
+func cellText(v interface{}) string {
+	s := text(v)
+	if _, ok := v.(string); ok && s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func (w *csvWriter) Write(p models.Product) error {
+	record := make([]string, len(w.columns))
+	for i, c := range w.columns {
+		record[i] = cellText(value(p, c))
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type jsonLinesWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+func (w *jsonLinesWriter) Write(p models.Product) error {
+	record := make(map[string]interface{}, len(w.columns))
+	for _, c := range w.columns {
+		record[c] = value(p, c)
+	}
+	return w.enc.Encode(record)
+}
+
+func (w *jsonLinesWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,222 @@
+// Package feed builds the Google Merchant product feed (RSS 2.0 with the g: namespace)
+// and keeps a cached copy that is rebuilt whenever the products change.
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxAdditionalImages is the number of extra images Google Merchant accepts per item
+	maxAdditionalImages = 10
+	// versionCheckInterval is how long the cached feed is served before checking whether the products changed.
+	// The check scans the products, and the feed is public, so it must not run on every request.
+	versionCheckInterval = 30 * time.Second
+)
+
+type rss struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	XMLNSG  string   `xml:"xmlns:g,attr"`
+	Channel channel  `xml:"channel"`
+}
+
+type channel struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Items       []item `xml:"item"`
+}
+
+type item struct {
+	ID               string   `xml:"g:id"`
+	Title            string   `xml:"title"`
+	Description      string   `xml:"description"`
+	Link             string   `xml:"link"`
+	ImageLink        string   `xml:"g:image_link,omitempty"`
+	AdditionalImages []string `xml:"g:additional_image_link,omitempty"`
+	Price            string   `xml:"g:price"`
+	SalePrice        string   `xml:"g:sale_price,omitempty"`
+	Availability     string   `xml:"g:availability"`
+	Condition        string   `xml:"g:condition"`
+	Brand            string   `xml:"g:brand,omitempty"`
+	GTIN             string   `xml:"g:gtin,omitempty"`
+	MPN              string   `xml:"g:mpn,omitempty"`
+	IdentifierExists string   `xml:"g:identifier_exists,omitempty"`
+}
+
+// Generate builds the feed from every product that is not archived.
+// STORE_URL is the storefront base URL used for product links, FEED_TITLE names the feed
+// and FEED_CURRENCY (default USD) is appended to prices. Google Merchant only accepts absolute
+// image links, so relative storage URLs are resolved against API_URL (default http://HOST:PORT).
+func Generate(db *gorm.DB) ([]byte, error) {
+	storeURL := strings.TrimRight(os.Getenv("STORE_URL"), "/")
+	apiURL, err := url.Parse(apiBaseURL())
+	if err != nil {
+		return nil, fmt.Errorf("invalid API_URL: %w", err)
+	}
+	title := os.Getenv("FEED_TITLE")
+	if title == "" {
+		title = "Products"
+	}
+	currency := os.Getenv("FEED_CURRENCY")
+	if currency == "" {
+		currency = "USD"
+	}
+
+	var products []models.Product
+	err = db.Order("id").
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, id ASC") }).
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	doc := rss{
+		Version: "2.0",
+		XMLNSG:  "http://base.google.com/ns/1.0",
+		Channel: channel{
+			Title:       title,
+			Link:        storeURL,
+			Description: title,
+			Items:       make([]item, 0, len(products)),
+		},
+	}
+
+	for _, p := range products {
+		it := item{
+			ID:          strconv.FormatUint(uint64(p.ID), 10),
+			Title:       p.Name,
+			Description: p.Description,
+			Link:        fmt.Sprintf("%s/products/%d", storeURL, p.ID),
+			Price:       fmt.Sprintf("%.2f %s", p.Price, currency),
+			// Stock is not tracked yet, so everything in the catalog is available
+			Availability: "in stock",
+			Condition:    "new",
+			Brand:        p.Attributes["brand"],
+			GTIN:         p.GTIN,
+		}
+		if p.SalePrice != nil {
+			it.SalePrice = fmt.Sprintf("%.2f %s", *p.SalePrice, currency)
+		}
+		for i, img := range p.Images {
+			link := imageURL(apiURL, img.Key)
+			if i == 0 {
+				it.ImageLink = link
+			} else if len(it.AdditionalImages) < maxAdditionalImages {
+				it.AdditionalImages = append(it.AdditionalImages, link)
+			}
+		}
+		if p.SKU != "" {
+			it.ID = p.SKU
+			it.MPN = p.SKU
+		}
+		if it.Description == "" {
+			it.Description = p.Name
+		}
+		if it.GTIN == "" && it.Brand == "" {
+			it.IdentifierExists = "no"
+		}
+		doc.Channel.Items = append(doc.Channel.Items, it)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func apiBaseURL() string {
+	if v := os.Getenv("API_URL"); v != "" {
+		return v
+	}
+	host := os.Getenv("HOST")
+	if host == "" {
+		host = "localhost"
+	}
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	return fmt.Sprintf("http://%s:%s", host, port)
+}
+
+// imageURL is the storage URL of key, made absolute against base if storage hands out relative
+// links (the local backend's default /media)
+func imageURL(base *url.URL, key string) string {
+	raw := storage.Default.URL(key)
+	link, err := url.Parse(raw)
+	if err != nil || link.IsAbs() {
+		return raw
+	}
+	return base.ResolveReference(link).String()
+}
+
+var cache struct {
+	sync.Mutex
+	data        []byte
+	version     string
+	generatedAt time.Time
+	checkedAt   time.Time
+}
+
+// Get returns the cached feed, regenerating it first if the products changed since it was built.
+// Changes show up within versionCheckInterval.
+func Get(db *gorm.DB) ([]byte, time.Time, error) {
+	cache.Lock()
+	defer cache.Unlock()
+
+	if cache.data != nil && time.Since(cache.checkedAt) < versionCheckInterval {
+		return cache.data, cache.generatedAt, nil
+	}
+
+	// Read before generating: a change committed in between makes the next call rebuild again
+	// rather than being missed
+	v, err := version(db)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if cache.data == nil || cache.version != v {
+		data, err := Generate(db)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		cache.data = data
+		cache.version = v
+		cache.generatedAt = time.Now()
+	}
+	cache.checkedAt = time.Now()
+	return cache.data, cache.generatedAt, nil
+}
+
+// version summarises the committed state of the products and images the feed is built from. Every create,
+// update, archive or delete changes it, whichever process made the change, and unlike max(updated_at)
+// it also changes when a transaction that started earlier commits late.
+func version(db *gorm.DB) (string, error) {
+	var v string
+	err := db.Raw(`SELECT
+		(SELECT COUNT(*) || ':' ||
+			COALESCE(SUM(EXTRACT(EPOCH FROM updated_at)), 0) || ':' ||
+			COALESCE(SUM(EXTRACT(EPOCH FROM deleted_at)), 0)
+		FROM products) || '/' ||
+		(SELECT COUNT(*) || ':' || COALESCE(SUM(EXTRACT(EPOCH FROM updated_at)), 0)
+		FROM product_images)`).Scan(&v).Error
+	return v, err
+}
@@ -0,0 +1,178 @@
+// Package importer loads products in bulk from CSV or JSON Lines files,
+// upserting them by SKU and recording the outcome on a models.ImportJob.
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/Emibrown/E-commerce-API/pricing"
+	"gorm.io/gorm"
+)
+
+const (
+	// progressEvery is how many rows are processed between progress updates on the job
+	progressEvery = 100
+	// maxReportedErrors caps the per-row error report stored on the job
+	maxReportedErrors = 1000
+)
+
+// Run parses data and imports every row, keeping job's counters and status up to date in the database.
+// Rows are committed one at a time, so a failing row doesn't undo the ones before it.
+func Run(db *gorm.DB, job *models.ImportJob, data []byte) error {
+	now := time.Now()
+	job.Status = models.ImportRunning
+	job.StartedAt = &now
+	if err := db.Save(job).Error; err != nil {
+		return err
+	}
+
+	format, err := ParseFormat(job.Format)
+	if err != nil {
+		return fail(db, job, err)
+	}
+
+	rows, err := Parse(bytes.NewReader(data), format)
+	if err != nil {
+		return fail(db, job, err)
+	}
+
+	job.TotalRows = len(rows)
+	seen := make(map[string]int, len(rows))
+	for i, row := range rows {
+		var created bool
+		if line, dup := seen[row.SKU]; dup {
+			// Checked here rather than left to the database, so a dry run reports it too
+			err = fmt.Errorf("duplicate sku, already in row %d", line)
+		} else {
+			created, err = importRow(db, row, job.DryRun)
+			// Only rows that went through claim their SKU, so a later row can fix a failed one
+			if err == nil {
+				seen[row.SKU] = row.Line
+			}
+		}
+		switch {
+		case err != nil:
+			job.FailedCount++
+			if len(job.Errors) < maxReportedErrors {
+				job.Errors = append(job.Errors, models.ImportRowError{Row: row.Line, SKU: row.SKU, Error: err.Error()})
+			}
+		case created:
+			job.CreatedCount++
+		default:
+			job.UpdatedCount++
+		}
+		job.ProcessedRows++
+
+		if (i+1)%progressEvery == 0 {
+			db.Save(job)
+		}
+	}
+
+	finished := time.Now()
+	job.Status = models.ImportCompleted
+	job.FinishedAt = &finished
+	return db.Save(job).Error
+}
+
+// importRow creates or updates the product identified by the row's SKU.
+// In a dry run nothing is written, but the row is still validated and matched.
+func importRow(db *gorm.DB, row Row, dryRun bool) (created bool, err error) {
+	if row.Err != nil {
+		return false, row.Err
+	}
+	if err := validate(row); err != nil {
+		return false, err
+	}
+
+	// Archived products keep their SKU, so they are updated in place rather than duplicated
+	var product models.Product
+	err = db.Unscoped().Where("sku = ?", row.SKU).First(&product).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	exists := err == nil
+
+	if dryRun {
+		return !exists, nil
+	}
+
+	if !exists {
+		product = models.Product{
+			SKU:         row.SKU,
+			GTIN:        row.GTIN,
+			Name:        row.Name,
+			Description: row.Description,
+			Attributes:  row.Attributes,
+			Price:       row.Price,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&product).Error; err != nil {
+				return err
+			}
+			return pricing.RecordChange(tx, product.ID, models.PriceRegular, nil, &product.Price, models.PriceSourceImport, nil)
+		})
+		return true, err
+	}
+
+	// Optional fields left empty in the file keep their current value
+	updates := map[string]interface{}{
+		"name":    row.Name,
+		"price":   row.Price,
+		"version": gorm.Expr("version + 1"),
+	}
+	if row.GTIN != "" {
+		updates["gtin"] = row.GTIN
+	}
+	if row.Description != "" {
+		updates["description"] = row.Description
+	}
+	if row.Attributes != nil {
+		updates["attributes"] = models.Attributes(row.Attributes)
+	}
+
+	oldPrice := product.Price
+	return false, db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&product).Updates(updates).Error; err != nil {
+			return err
+		}
+		return pricing.RecordChange(tx, product.ID, models.PriceRegular, &oldPrice, &row.Price, models.PriceSourceImport, nil)
+	})
+}
+
+func validate(row Row) error {
+	switch {
+	case row.SKU == "":
+		return errors.New("sku is required")
+	case row.Name == "":
+		return errors.New("name is required")
+	case row.Price <= 0:
+		return errors.New("price must be greater than 0")
+	}
+	return nil
+}
+
+// FailInterrupted marks imports started by the API that were still queued or running as failed.
+// They run inside the API process, so after a restart nothing is left to finish them.
+// Imports from the command line run in their own process and are left alone.
+func FailInterrupted(db *gorm.DB) error {
+	return db.Model(&models.ImportJob{}).
+		Where("status IN ? AND created_by IS NOT NULL", []models.ImportJobStatus{models.ImportQueued, models.ImportRunning}).
+		Updates(map[string]interface{}{
+			"status":      models.ImportFailed,
+			"message":     "interrupted by a server restart",
+			"finished_at": time.Now(),
+		}).Error
+}
+
+func fail(db *gorm.DB, job *models.ImportJob, err error) error {
+	finished := time.Now()
+	job.Status = models.ImportFailed
+	job.Message = err.Error()
+	job.FinishedAt = &finished
+	db.Save(job)
+	return err
+}
@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	CSV       Format = "csv"
+	JSONLines Format = "jsonl"
+)
+
+// ParseFormat validates a format name given by the user
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case CSV:
+		return CSV, nil
+	case JSONLines, "jsonlines", "ndjson":
+		return JSONLines, nil
+	}
+	return "", fmt.Errorf("unsupported import format %q (expected csv or jsonl)", s)
+}
+
+// FormatFromFilename guesses the format from a file extension
+func FormatFromFilename(name string) (Format, bool) {
+	f, err := ParseFormat(strings.TrimPrefix(filepath.Ext(name), "."))
+	return f, err == nil
+}
+
+// Row is one product record read from an import file
+type Row struct {
+	Line        int               `json:"-"`
+	SKU         string            `json:"sku"`
+	GTIN        string            `json:"gtin"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Attributes  map[string]string `json:"attributes"`
+	Price       float64           `json:"price"`
+
+	// Err is set when the row itself could not be parsed
+	Err error `json:"-"`
+}
+
+var csvColumns = map[string]bool{"sku": true, "gtin": true, "name": true, "description": true, "price": true, "attributes": true}
+
+// Parse reads every row of an import file. A malformed row is returned with Err set
+// rather than aborting the import; only an unusable file as a whole returns an error.
+func Parse(r io.Reader, format Format) ([]Row, error) {
+	switch format {
+	case CSV:
+		return parseCSV(r)
+	case JSONLines:
+		return parseJSONLines(r)
+	}
+	return nil, fmt.Errorf("unsupported import format %q", format)
+}
+
+func parseCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !csvColumns[name] {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"sku", "name", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing required CSV column %q", required)
+		}
+	}
+
+	var rows []Row
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		row := Row{Line: line}
+		if err != nil {
+			row.Err = err
+			rows = append(rows, row)
+			continue
+		}
+
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row.SKU = get("sku")
+		row.GTIN = get("gtin")
+		row.Name = get("name")
+		row.Description = get("description")
+
+		if price := get("price"); price != "" {
+			if row.Price, err = strconv.ParseFloat(price, 64); err != nil {
+				row.Err = fmt.Errorf("invalid price %q", price)
+			}
+		}
+		if attrs := get("attributes"); attrs != "" && row.Err == nil {
+			if err := json.Unmarshal([]byte(attrs), &row.Attributes); err != nil {
+				row.Err = errors.New("attributes must be a JSON object of strings")
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseJSONLines(r io.Reader) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var rows []Row
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		row := Row{Line: line}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			row = Row{Line: line, Err: fmt.Errorf("invalid JSON: %v", err)}
+		}
+		row.SKU = strings.TrimSpace(row.SKU)
+		row.Name = strings.TrimSpace(row.Name)
+
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read file: %w", err)
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,108 @@
+package jwtkeys
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// clockSkew is how far the clocks of the issuer and a verifier may differ
+const clockSkew = 30 * time.Second
+
+const defaultIssuer = "e-commerce-api"
+
+// Reasons an access token is rejected
+var (
+	ErrMalformed        = errors.New("token is malformed")
+	ErrUnknownKey       = errors.New("token is signed with an unknown key")
+	ErrUnexpectedMethod = errors.New("token is signed with an unexpected algorithm")
+	ErrInvalidSignature = errors.New("token signature is invalid")
+	ErrExpired          = errors.New("token has expired")
+	ErrNotYetValid      = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("token issuer is invalid")
+	ErrInvalidAudience  = errors.New("token audience is invalid")
+	ErrInvalidClaims    = errors.New("token claims are invalid")
+)
+
+// AccessClaims are the claims of an access token
+type AccessClaims struct {
+	UserID    uint `json:"user_id"`
+	SessionID uint `json:"sid"`
+	jwt.StandardClaims
+}
+
+// Valid always passes; the claims are checked by ParseAccessToken once the signature is known to be good
+func (c AccessClaims) Valid() error {
+	return nil
+}
+
+// IssueAccessToken signs an access token for a user's session, valid for ttl
+func (ks *KeySet) IssueAccessToken(userID, sessionID uint, jti string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	return ks.Sign(AccessClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Issuer:    ks.Issuer,
+			Audience:  ks.Audience,
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	})
+}
+
+// ParseAccessToken verifies an access token and returns its claims. Errors are one of the Err values above.
+func (ks *KeySet) ParseAccessToken(tokenString string) (*AccessClaims, error) {
+	claims := &AccessClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, ks.keyFunc)
+	if err != nil {
+		var ve *jwt.ValidationError
+		switch {
+		case !errors.As(err, &ve):
+			return nil, ErrMalformed
+		case errors.Is(ve.Inner, ErrUnknownKey), errors.Is(ve.Inner, ErrUnexpectedMethod):
+			return nil, ve.Inner
+		case ve.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+			return nil, ErrInvalidSignature
+		case ve.Errors&jwt.ValidationErrorUnverifiable != 0:
+			// The "alg" header names an algorithm jwt-go doesn't know
+			return nil, ErrUnexpectedMethod
+		default:
+			return nil, ErrMalformed
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case claims.UserID == 0 || claims.SessionID == 0 || claims.ExpiresAt == 0:
+		return nil, ErrInvalidClaims
+	case now.Add(-clockSkew).Unix() >= claims.ExpiresAt:
+		return nil, ErrExpired
+	case claims.NotBefore != 0 && now.Add(clockSkew).Unix() < claims.NotBefore:
+		return nil, ErrNotYetValid
+	case claims.Issuer != ks.Issuer:
+		return nil, ErrInvalidIssuer
+	case claims.Audience != ks.Audience:
+		return nil, ErrInvalidAudience
+	}
+	return claims, nil
+}
+
+// keyFunc picks the key named by the token's "kid" header. The token must be signed with that key's
+// algorithm, so a token can't pick a weaker algorithm than its key's, nor "none".
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnexpectedMethod
+	}
+	return key.Public, nil
+}
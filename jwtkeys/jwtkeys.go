@@ -0,0 +1,223 @@
+// Package jwtkeys signs and verifies the API's access tokens.
+//
+// Tokens are signed with an RSA (RS256) or Ed25519 (EdDSA) private key and carry its key ID in the
+// "kid" header. Any number of further public keys can be trusted for verification, so a new signing key
+// can be rolled out while tokens signed with the old one are still in use. The public keys are published
+// as a JSON Web Key Set for other services. Without any keys configured, tokens fall back to HS256
+// with JWT_SECRET.
+package jwtkeys
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Key is a key tokens are verified with, and signed with if the private half is known
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Public  interface{} // *rsa.PublicKey, ed25519.PublicKey or, for HS256, the secret
+	Private interface{} // *rsa.PrivateKey, ed25519.PrivateKey, the HS256 secret or nil
+}
+
+// KeySet holds the signing key and every key accepted for verification, by ID
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+
+	// Issuer and Audience are put into access tokens and required of them
+	Issuer   string
+	Audience string
+}
+
+// Default is the key set used by the API, set up by Init
+var Default *KeySet
+
+// Init loads the keys from the environment:
+//
+//   - JWT_KEYS_DIR: a directory of PEM files, each holding one private or public key; the file name
+//     without extension is the key ID
+//   - JWT_PRIVATE_KEY: a PEM private key, with the key ID from JWT_KEY_ID (default derived from the key)
+//   - JWT_SIGNING_KEY_ID: which private key signs; needed when more than one is loaded
+//
+// Without any of them, HS256 with JWT_SECRET is used. JWT_ISSUER and JWT_AUDIENCE (both default
+// "e-commerce-api") set the "iss" and "aud" claims.
+func Init() error {
+	ks, err := Load(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_PRIVATE_KEY"), os.Getenv("JWT_KEY_ID"), os.Getenv("JWT_SIGNING_KEY_ID"))
+	if err != nil {
+		return err
+	}
+	if v := os.Getenv("JWT_ISSUER"); v != "" {
+		ks.Issuer = v
+	}
+	if v := os.Getenv("JWT_AUDIENCE"); v != "" {
+		ks.Audience = v
+	}
+	Default = ks
+	return nil
+}
+
+// Load builds a key set from a key directory and/or a PEM private key, falling back to HS256 with JWT_SECRET
+func Load(dir, privatePEM, privateKeyID, signingKeyID string) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]*Key{}, Issuer: defaultIssuer, Audience: defaultIssuer}
+
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			key, err := parseKey(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			key.ID = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			if err := ks.add(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if privatePEM != "" {
+		key, err := parseKey([]byte(privatePEM))
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY: %w", err)
+		}
+		if key.Private == nil {
+			return nil, errors.New("JWT_PRIVATE_KEY holds a public key")
+		}
+		key.ID = privateKeyID
+		if key.ID == "" {
+			key.ID = thumbprint(key)
+		}
+		if err := ks.add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ks.keys) == 0 {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("no signing key: set JWT_KEYS_DIR, JWT_PRIVATE_KEY or JWT_SECRET")
+		}
+		key := &Key{Method: jwt.SigningMethodHS256, Public: []byte(secret), Private: []byte(secret)}
+		ks.keys[""] = key
+		ks.signing = key
+		return ks, nil
+	}
+
+	if signingKeyID != "" {
+		key, ok := ks.keys[signingKeyID]
+		if !ok || key.Private == nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY_ID %q is not a loaded private key", signingKeyID)
+		}
+		ks.signing = key
+		return ks, nil
+	}
+
+	for _, key := range ks.keys {
+		if key.Private == nil {
+			continue
+		}
+		if ks.signing != nil {
+			return nil, errors.New("several private keys are loaded, set JWT_SIGNING_KEY_ID to pick one")
+		}
+		ks.signing = key
+	}
+	if ks.signing == nil {
+		return nil, errors.New("no private key to sign tokens with")
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) add(key *Key) error {
+	if _, exists := ks.keys[key.ID]; exists {
+		return fmt.Errorf("duplicate key ID %q", key.ID)
+	}
+	ks.keys[key.ID] = key
+	return nil
+}
+
+// Sign signs claims with the signing key
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.ID != "" {
+		token.Header["kid"] = ks.signing.ID
+	}
+	return token.SignedString(ks.signing.Private)
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // Ed25519 curve
+	X   string `json:"x,omitempty"`   // Ed25519 public key
+}
+
+// JWKSet is a JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns every public key tokens are verified with, sorted by ID. HS256 secrets are never included.
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if jwk, ok := toJWK(key); ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func toJWK(key *Key) (JWK, bool) {
+	enc := base64.RawURLEncoding
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: key.ID,
+			Use: "sig",
+			Alg: key.Method.Alg(),
+			N:   enc.EncodeToString(pub.N.Bytes()),
+			E:   enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Kid: key.ID, Use: "sig", Alg: key.Method.Alg(), Crv: "Ed25519", X: enc.EncodeToString(pub)}, true
+	}
+	return JWK{}, false
+}
+
+// thumbprint derives a key ID from the public key (RFC 7638), so it stays the same across restarts
+func thumbprint(key *Key) string {
+	jwk, _ := toJWK(key)
+	var members string
+	if jwk.Kty == "RSA" {
+		members = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, jwk.E, jwk.N)
+	} else {
+		members = fmt.Sprintf(`{"crv":"Ed25519","kty":"OKP","x":"%s"}`, jwk.X)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
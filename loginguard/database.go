@@ -0,0 +1,59 @@
+package loginguard
+
+import (
+	"context"
+
+	"github.com/Emibrown/E-commerce-API/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBStore keeps counters in the login_attempt_counters table, so they survive restarts
+// and are shared by every instance of the API
+type DBStore struct {
+	db *gorm.DB
+}
+
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+func (s *DBStore) Get(ctx context.Context, key string) (Counter, error) {
+	var row models.LoginAttemptCounter
+	if err := s.db.WithContext(ctx).Where("key = ?", key).Limit(1).Find(&row).Error; err != nil {
+		return Counter{}, err
+	}
+	return toCounter(row), nil
+}
+
+func (s *DBStore) Update(ctx context.Context, key string, fn func(*Counter)) (Counter, error) {
+	var counter Counter
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Make sure the row exists, then lock it so concurrent failures all get counted
+		row := models.LoginAttemptCounter{Key: key}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&row).Error; err != nil {
+			return err
+		}
+
+		counter = toCounter(row)
+		fn(&counter)
+
+		return tx.Model(&row).Updates(map[string]interface{}{
+			"failures":     counter.Failures,
+			"last_failure": counter.LastFailure,
+			"locked_until": counter.LockedUntil,
+		}).Error
+	})
+	return counter, err
+}
+
+func (s *DBStore) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("key = ?", key).Delete(&models.LoginAttemptCounter{}).Error
+}
+
+func toCounter(row models.LoginAttemptCounter) Counter {
+	return Counter{Failures: row.Failures, LastFailure: row.LastFailure, LockedUntil: row.LockedUntil}
+}
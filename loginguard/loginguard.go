@@ -0,0 +1,208 @@
+// Package loginguard slows down password guessing by counting failed logins per account and per client IP.
+// Every failure past a free allowance locks the key for exponentially longer, up to a temporary lockout.
+package loginguard
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Counter is the failure state of one key
+type Counter struct {
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// AttemptStore keeps counters by key. Unknown keys read as a zero Counter.
+type AttemptStore interface {
+	Get(ctx context.Context, key string) (Counter, error)
+	// Update applies fn to the counter of key atomically and returns the result
+	Update(ctx context.Context, key string, fn func(*Counter)) (Counter, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Policy decides how long a key is locked after a number of consecutive failures
+type Policy struct {
+	FreeFailures int           // failures allowed before any delay
+	BaseDelay    time.Duration // delay after the first failure past FreeFailures, doubling with each further one
+	MaxFailures  int           // failures after which the key is locked out for Lockout
+	Lockout      time.Duration
+	Window       time.Duration // failures older than this are forgotten
+}
+
+// Delay returns how long a key is locked after failures consecutive failures
+func (p Policy) Delay(failures int) time.Duration {
+	if failures >= p.MaxFailures {
+		return p.Lockout
+	}
+	if failures <= p.FreeFailures {
+		return 0
+	}
+
+	d := p.BaseDelay
+	for i := p.FreeFailures + 1; i < failures && d < p.Lockout; i++ {
+		d *= 2
+	}
+	if d > p.Lockout {
+		return p.Lockout
+	}
+	return d
+}
+
+// Guard applies an account policy and an IP policy on top of a store
+type Guard struct {
+	Store   AttemptStore
+	Account Policy
+	IP      Policy
+}
+
+// Default is the guard used by the API, set up by Init
+var Default = &Guard{Store: NewMemoryStore(), Account: defaultAccountPolicy, IP: defaultIPPolicy}
+
+var (
+	defaultAccountPolicy = Policy{FreeFailures: 3, BaseDelay: time.Second, MaxFailures: 10, Lockout: 15 * time.Minute, Window: time.Hour}
+	defaultIPPolicy      = Policy{FreeFailures: 20, BaseDelay: time.Second, MaxFailures: 100, Lockout: 15 * time.Minute, Window: time.Hour}
+)
+
+// Init picks the counter store from LOGIN_GUARD_STORE ("memory" or "database") and reads the limits
+// LOGIN_MAX_FAILURES, LOGIN_IP_MAX_FAILURES and LOGIN_LOCKOUT_DURATION
+func Init(db *gorm.DB) error {
+	guard := &Guard{Account: defaultAccountPolicy, IP: defaultIPPolicy}
+
+	switch store := os.Getenv("LOGIN_GUARD_STORE"); store {
+	case "", "memory":
+		guard.Store = NewMemoryStore()
+	case "database":
+		guard.Store = NewDBStore(db)
+	default:
+		return fmt.Errorf("unknown LOGIN_GUARD_STORE %q (expected memory or database)", store)
+	}
+
+	guard.Account.MaxFailures = envInt("LOGIN_MAX_FAILURES", guard.Account.MaxFailures)
+	guard.IP.MaxFailures = envInt("LOGIN_IP_MAX_FAILURES", guard.IP.MaxFailures)
+	if v := os.Getenv("LOGIN_LOCKOUT_DURATION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Printf("Invalid LOGIN_LOCKOUT_DURATION %q, using %s", v, guard.Account.Lockout)
+		} else {
+			guard.Account.Lockout, guard.IP.Lockout = d, d
+		}
+	}
+
+	Default = guard
+	return nil
+}
+
+// Reserve counts a login for email from ip as failed before the password is checked, so concurrent
+// guesses can't all get past a lock that the first of them would set. Once the password turns out to
+// be right the attempt is taken back with Refund or Succeed. If either key is locked nothing is counted
+// and Reserve returns how long logins must wait.
+func (g *Guard) Reserve(ctx context.Context, email, ip string) (time.Duration, error) {
+	wait, err := g.Hit(ctx, accountKey(email), g.Account)
+	if err != nil || wait > 0 {
+		return wait, err
+	}
+	wait, err = g.Hit(ctx, ipKey(ip), g.IP)
+	if err != nil || wait > 0 {
+		if rerr := g.refund(ctx, accountKey(email), g.Account); rerr != nil {
+			log.Printf("Refunding login attempt for %q failed: %v", email, rerr)
+		}
+	}
+	return wait, err
+}
+
+// Refund takes back an attempt counted by Reserve
+func (g *Guard) Refund(ctx context.Context, email, ip string) error {
+	if err := g.refund(ctx, accountKey(email), g.Account); err != nil {
+		return err
+	}
+	return g.refund(ctx, ipKey(ip), g.IP)
+}
+
+// Succeed clears the failures of an account after a successful login and takes back the attempt counted
+// against the IP. The rest of the IP counter is left alone, so logging into one's own account doesn't lift
+// the limit on guessing others.
+func (g *Guard) Succeed(ctx context.Context, email, ip string) error {
+	if err := g.Store.Delete(ctx, accountKey(email)); err != nil {
+		return err
+	}
+	return g.refund(ctx, ipKey(ip), g.IP)
+}
+
+// Unlock lifts the lockout of an account
+func (g *Guard) Unlock(ctx context.Context, email string) error {
+	return g.Store.Delete(ctx, accountKey(email))
+}
+
+// Status returns the counter of an account
+func (g *Guard) Status(ctx context.Context, email string) (Counter, error) {
+	return g.Store.Get(ctx, accountKey(email))
+}
+
+// Hit counts one use of a rate limited action under key, such as password reset requests from an IP,
+// and returns how long the caller must wait if the key is locked. Hits while locked are not counted.
+func (g *Guard) Hit(ctx context.Context, key string, p Policy) (time.Duration, error) {
+	now := time.Now()
+	var wait time.Duration
+	_, err := g.Store.Update(ctx, key, func(c *Counter) {
+		if now.Before(c.LockedUntil) {
+			wait = c.LockedUntil.Sub(now)
+			return
+		}
+		p.fail(now)(c)
+	})
+	return wait, err
+}
+
+// refund takes one failure off the counter of key, lifting the lock it added
+func (g *Guard) refund(ctx context.Context, key string, p Policy) error {
+	_, err := g.Store.Update(ctx, key, func(c *Counter) {
+		if c.Failures > 0 {
+			c.Failures--
+			c.LockedUntil = c.LastFailure.Add(p.Delay(c.Failures))
+		}
+	})
+	return err
+}
+
+// fail returns the update recording one failure at now
+func (p Policy) fail(now time.Time) func(*Counter) {
+	return func(c *Counter) {
+		if now.Sub(c.LastFailure) > p.Window {
+			c.Failures = 0
+		}
+		c.Failures++
+		c.LastFailure = now
+		c.LockedUntil = now.Add(p.Delay(c.Failures))
+	}
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// envInt reads a positive number from the environment, falling back to def when unset or invalid
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid %s %q, using %d", name, v, def)
+		return def
+	}
+	return n
+}
@@ -0,0 +1,59 @@
+package loginguard
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneEvery is how many updates the memory store lets pass between sweeps for stale counters
+const pruneEvery = 1000
+
+// MemoryStore keeps counters in memory. Counters are lost on restart and not shared between instances.
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]Counter
+	updates  int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: map[string]Counter{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counters[key], nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, fn func(*Counter)) (Counter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := s.counters[key]
+	fn(&counter)
+	s.counters[key] = counter
+
+	s.updates++
+	if s.updates%pruneEvery == 0 {
+		s.prune(time.Now())
+	}
+	return counter, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counters, key)
+	return nil
+}
+
+// prune drops counters that are no longer locked and whose last failure is a day old,
+// which is longer than any window in use
+func (s *MemoryStore) prune(now time.Time) {
+	for key, c := range s.counters {
+		if now.After(c.LockedUntil) && now.Sub(c.LastFailure) > 24*time.Hour {
+			delete(s.counters, key)
+		}
+	}
+}
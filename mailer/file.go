@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// File writes each message as an .eml file into a directory, for local development and end-to-end tests
+type File struct {
+	Dir string
+	seq uint64
+}
+
+func NewFile(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &File{Dir: dir}, nil
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	n := atomic.AddUint64(&f.seq, 1)
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().UTC().Format("20060102T150405.000000000"), n)
+	return os.WriteFile(filepath.Join(f.Dir, name), render("", msg), 0o644)
+}
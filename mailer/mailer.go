@@ -0,0 +1,85 @@
+// Package mailer sends transactional emails (password resets, verification links, ...) through a pluggable backend
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Default is the mailer used by the API, set up by Init
+var Default Mailer = Log{}
+
+// Init picks the mailer from the MAIL_DRIVER environment variable ("log", "file", "memory" or "smtp")
+func Init() error {
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "":
+		log.Print("MAIL_DRIVER is not set, emails are only logged and their links are redacted")
+		Default = Log{}
+	case "log":
+		Default = Log{}
+	case "memory":
+		Default = NewMemory()
+	case "file":
+		dir := os.Getenv("MAIL_FILE_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		file, err := NewFile(dir)
+		if err != nil {
+			return err
+		}
+		Default = file
+	case "smtp":
+		port := 587
+		if v := os.Getenv("SMTP_PORT"); v != "" {
+			p, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid SMTP_PORT %q", v)
+			}
+			port = p
+		}
+		smtp, err := NewSMTP(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+		if err != nil {
+			return err
+		}
+		Default = smtp
+	default:
+		return fmt.Errorf("unknown MAIL_DRIVER %q (expected log, file, memory or smtp)", driver)
+	}
+	return nil
+}
+
+// tokenParam matches the token query parameter of links in emails
+var tokenParam = regexp.MustCompile(`([?&]token=)[^&\s]+`)
+
+// Log writes messages to the application log instead of sending them, which is handy during development.
+// Tokens in links are redacted, since logs are read by more people than the recipient; use the file
+// or memory driver to get at working links.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, tokenParam.ReplaceAllString(msg.Body, "${1}[redacted]"))
+	return nil
+}
@@ -0,0 +1,168 @@
+// Package media decodes uploaded product images and produces resized thumbnails
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+)
+
+// ThumbnailSize is a named bounding box thumbnails are scaled down to fit in
+type ThumbnailSize struct {
+	Name string
+	Max  int // maximum width and height in pixels
+}
+
+// ThumbnailSizes are generated for every uploaded image
+var ThumbnailSizes = []ThumbnailSize{
+	{Name: "small", Max: 150},
+	{Name: "medium", Max: 400},
+	{Name: "large", Max: 800},
+}
+
+// MaxPixels caps width x height of an upload. A decoded image takes 4 bytes per pixel, so a small
+// file declaring huge dimensions would otherwise exhaust memory.
+const MaxPixels = 25_000_000
+
+var (
+	// ErrUnsupportedFormat is returned for uploads that are not JPEG, PNG or GIF images
+	ErrUnsupportedFormat = errors.New("unsupported image format (expected JPEG, PNG or GIF)")
+	// ErrTooLarge is returned for images with more than MaxPixels pixels
+	ErrTooLarge = fmt.Errorf("image is too large (at most %d megapixels)", MaxPixels/1_000_000)
+)
+
+// Decode reads a JPEG, PNG or GIF image and reports its format name. The dimensions are checked
+// against MaxPixels before any pixel data is decoded. The image is returned as NRGBA, ready for Thumbnail.
+func Decode(data []byte) (*image.NRGBA, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, "", ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(config.Width)*int64(config.Height) > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba, format, nil
+	}
+	b := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+	return nrgba, format, nil
+}
+
+// Thumbnail scales src, as returned by Decode, down (never up) to fit in a max x max box, keeping its aspect ratio
+func Thumbnail(src *image.NRGBA, max int) image.Image {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w <= max && h <= max {
+		return src
+	}
+
+	dw, dh := max, h*max/w
+	if h > w {
+		dw, dh = w*max/h, max
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	// Box filter: every destination pixel is the average of the source pixels it covers
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, (y+1)*h/dh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, (x+1)*w/dw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					bl += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			o := dst.PixOffset(x, y)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(bl / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// EncodeThumbnail writes a thumbnail of an image in the given format and returns it with its content type.
+// GIFs become PNGs, since thumbnails are not animated.
+func EncodeThumbnail(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	case "png", "gif":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	return nil, "", ErrUnsupportedFormat
+}
+
+// Extension is the file extension for an original upload in the given format
+func Extension(format string) string {
+	switch format {
+	case "jpeg":
+		return ".jpg"
+	case "png":
+		return ".png"
+	case "gif":
+		return ".gif"
+	}
+	return ""
+}
+
+// ContentType is the MIME type for an original upload in the given format
+func ContentType(format string) string {
+	return "image/" + format
+}
+
+// ThumbnailKey is the storage key of a thumbnail, stored next to the original under key.
+// The extension matches what EncodeThumbnail produces for format.
+func ThumbnailKey(key, format, size string) string {
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	return strings.TrimSuffix(key, path.Ext(key)) + "_" + size + ext
+}
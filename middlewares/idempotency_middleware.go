@@ -0,0 +1,161 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+	maxIdempotencyKeyLength  = 255
+)
+
+// Idempotency replays the stored response when a request is retried with the same
+// Idempotency-Key header. Keys are scoped to the authenticated user, so it must run after AuthMiddleware.
+// How long a key is remembered is configured through IDEMPOTENCY_KEY_TTL (e.g. "24h").
+func Idempotency() gin.HandlerFunc {
+	ttl := defaultIdempotencyKeyTTL
+	if v := os.Getenv("IDEMPOTENCY_KEY_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Printf("Invalid IDEMPOTENCY_KEY_TTL %q, using %s", v, defaultIdempotencyKeyTTL)
+		} else {
+			ttl = d
+		}
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		userID := c.GetUint("user_id")
+
+		var existing models.IdempotencyKey
+		err = config.DB.Where("user_id = ? AND key = ?", userID, key).Limit(1).Find(&existing).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check Idempotency-Key"})
+			c.Abort()
+			return
+		}
+
+		if existing.ID != 0 {
+			switch {
+			case time.Now().After(existing.ExpiresAt):
+				// The key has outlived its window, so it may be used again
+				config.DB.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
+			case existing.RequestHash != requestHash:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+				c.Abort()
+				return
+			case existing.StatusCode == 0:
+				c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+				c.Abort()
+				return
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(existing.StatusCode, existing.ContentType, existing.ResponseBody)
+				c.Abort()
+				return
+			}
+		}
+
+		// Claim the key before running the handler; the unique index makes a concurrent retry fail here
+		record := models.IdempotencyKey{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(ttl),
+		}
+		if err := config.DB.Create(&record).Error; err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still being processed"})
+			c.Abort()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// A panicking handler has done nothing worth replaying, so release the key for a retry
+		defer func() {
+			if r := recover(); r != nil {
+				releaseIdempotencyKey(record)
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		// Server errors are not stored, so the client can retry with the same key
+		if writer.Status() >= http.StatusInternalServerError {
+			releaseIdempotencyKey(record)
+			return
+		}
+
+		// If the response can't be stored the key stays claimed: the request went through, and running it
+		// again on retry could e.g. place a second order. Retries get a 409 until the key expires.
+		err = config.DB.Model(&record).Updates(models.IdempotencyKey{
+			StatusCode:   writer.Status(),
+			ContentType:  writer.Header().Get("Content-Type"),
+			ResponseBody: writer.body.Bytes(),
+		}).Error
+		if err != nil {
+			log.Printf("Storing response for Idempotency-Key %d failed: %v", record.ID, err)
+		}
+	}
+}
+
+func releaseIdempotencyKey(record models.IdempotencyKey) {
+	if err := config.DB.Delete(&record).Error; err != nil {
+		log.Printf("Releasing Idempotency-Key %d failed: %v", record.ID, err)
+	}
+}
+
+// recordingWriter keeps a copy of everything written to the response
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
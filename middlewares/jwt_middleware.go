@@ -1,49 +1,91 @@
 package middlewares
 
 import (
+	"errors"
 	"net/http"
-	"os"
 	"strings"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/jwtkeys"
+	"github.com/Emibrown/E-commerce-API/models"
 	"github.com/gin-gonic/gin"
 )
 
+// tokenErrors maps why a token was rejected to the code and message sent to the client
+var tokenErrors = []struct {
+	err     error
+	code    string
+	message string
+}{
+	{jwtkeys.ErrExpired, "token_expired", "Token has expired"},
+	{jwtkeys.ErrNotYetValid, "token_not_yet_valid", "Token is not valid yet"},
+	{jwtkeys.ErrInvalidSignature, "token_invalid_signature", "Token signature is invalid"},
+	{jwtkeys.ErrUnknownKey, "token_unknown_key", "Token is signed with an unknown key"},
+	{jwtkeys.ErrUnexpectedMethod, "token_invalid_algorithm", "Token is signed with an unexpected algorithm"},
+	{jwtkeys.ErrInvalidIssuer, "token_invalid_issuer", "Token was issued by someone else"},
+	{jwtkeys.ErrInvalidAudience, "token_invalid_audience", "Token is meant for someone else"},
+	{jwtkeys.ErrInvalidClaims, "token_invalid_claims", "Token claims are invalid"},
+}
+
+// AuthMiddleware lets through requests with a valid access token of an open session of an active account,
+// setting user_id and session_id. Rejections are 401s carrying a reason code next to the message.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
-			c.Abort()
+			unauthorized(c, "token_missing", "Authorization header missing")
 			return
 		}
 
-		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			c.Set("user_id", uint(claims["user_id"].(float64)))
-			c.Set("is_admin", claims["is_admin"].(bool))
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-			c.Abort()
+		scheme, tokenString, found := strings.Cut(authHeader, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(tokenString) == "" {
+			unauthorized(c, "token_malformed", "Authorization header must be a Bearer token")
 			return
 		}
 
-		c.Next()
-	}
-}
+		claims, err := jwtkeys.Default.ParseAccessToken(strings.TrimSpace(tokenString))
+		if err != nil {
+			code, message := "token_malformed", "Token is malformed"
+			for _, e := range tokenErrors {
+				if errors.Is(err, e.err) {
+					code, message = e.code, e.message
+					break
+				}
+			}
+			unauthorized(c, code, message)
+			return
+		}
 
-func AdminMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		isAdmin, exists := c.Get("is_admin")
-		if !exists || !isAdmin.(bool) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access only"})
+		// Tokens only stay valid while the session they were issued for is open
+		var session models.Session
+		err = config.DB.Where("id = ? AND user_id = ?", claims.SessionID, claims.UserID).Limit(1).Find(&session).Error
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check session"})
 			c.Abort()
 			return
 		}
+		if session.ID == 0 || session.RevokedAt != nil {
+			unauthorized(c, "session_revoked", "Session has been revoked")
+			return
+		}
+
+		var user models.User
+		if err := config.DB.Select("id", "disabled_at").First(&user, session.UserID).Error; err != nil {
+			unauthorized(c, "account_not_found", "Account no longer exists")
+			return
+		}
+		if user.DisabledAt != nil {
+			unauthorized(c, "account_disabled", "Account is disabled")
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("session_id", session.ID)
 		c.Next()
 	}
 }
+
+func unauthorized(c *gin.Context, code, message string) {
+	c.JSON(http.StatusUnauthorized, gin.H{"error": message, "code": code})
+	c.Abort()
+}
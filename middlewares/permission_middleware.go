@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/rbac"
+	"github.com/gin-gonic/gin"
+)
+
+// StaffMiddleware only lets through users holding at least one permission, i.e. staff members.
+// Permissions are read from the database on every request, so role changes apply immediately.
+// It must run after AuthMiddleware.
+func StaffMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, ok := loadPermissions(c)
+		if !ok {
+			return
+		}
+		if len(permissions) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Staff access only"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission only lets through users whose roles grant permission
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, ok := loadPermissions(c)
+		if !ok {
+			return
+		}
+		if !permissions[permission] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// loadPermissions returns the authenticated user's permissions, reading them once per request.
+// On failure it aborts the request and returns false.
+func loadPermissions(c *gin.Context) (map[string]bool, bool) {
+	if cached, exists := c.Get("permissions"); exists {
+		return cached.(map[string]bool), true
+	}
+
+	list, err := rbac.UserPermissions(config.DB, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check permissions"})
+		c.Abort()
+		return nil, false
+	}
+
+	permissions := make(map[string]bool, len(list))
+	for _, p := range list {
+		permissions[p] = true
+	}
+	c.Set("permissions", permissions)
+	return permissions, true
+}
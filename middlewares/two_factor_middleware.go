@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireStaffTwoFactor keeps staff without two-factor authentication out of the admin API when
+// REQUIRE_STAFF_2FA is true. They can still log in and set it up through /api/me/2fa.
+// It must run after AuthMiddleware.
+func RequireStaffTwoFactor() gin.HandlerFunc {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_STAFF_2FA"))
+
+	return func(c *gin.Context) {
+		if !required {
+			c.Next()
+			return
+		}
+
+		var user models.User
+		if err := config.DB.Select("id", "totp_enabled_at").First(&user, c.GetUint("user_id")).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+		if user.TOTPEnabledAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Staff accounts must enable two-factor authentication before using the admin API"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,38 @@
+package middlewares
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/Emibrown/E-commerce-API/config"
+	"github.com/Emibrown/E-commerce-API/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireVerifiedEmailForOrders blocks users whose email address isn't verified yet from placing orders,
+// when REQUIRE_VERIFIED_EMAIL_FOR_ORDERS is true. It must run after AuthMiddleware.
+func RequireVerifiedEmailForOrders() gin.HandlerFunc {
+	required, _ := strconv.ParseBool(os.Getenv("REQUIRE_VERIFIED_EMAIL_FOR_ORDERS"))
+
+	return func(c *gin.Context) {
+		if !required {
+			c.Next()
+			return
+		}
+
+		var user models.User
+		if err := config.DB.Select("id", "email_verified").First(&user, c.GetUint("user_id")).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+		if !user.EmailVerified {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address before placing orders"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// CustomerGroup is a set of customers (e.g. wholesale accounts) that share a price list
+type CustomerGroup struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"uniqueIndex; not null"`
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ProductPriceTier overrides a product's price for a customer group and/or from a minimum quantity.
+// A nil CustomerGroupID applies the tier to every customer.
+type ProductPriceTier struct {
+	ID              uint    `gorm:"primaryKey"`
+	ProductID       uint    `gorm:"not null;index"`
+	CustomerGroupID *uint   `gorm:"index"`
+	MinQuantity     int     `gorm:"not null;default:1"`
+	Price           float64 `gorm:"not null"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
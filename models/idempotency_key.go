@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the first response to a request sent with an Idempotency-Key header,
+// so that retries of the same request can be answered without repeating its side effects
+type IdempotencyKey struct {
+	ID           uint   `gorm:"primaryKey"`
+	UserID       uint   `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Key          string `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	RequestHash  string `gorm:"type:char(64);not null"` // sha256 of method, path and body
+	StatusCode   int    // zero while the original request is still being processed
+	ContentType  string
+	ResponseBody []byte
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type ImportJobStatus string
+
+const (
+	ImportQueued    ImportJobStatus = "Queued"
+	ImportRunning   ImportJobStatus = "Running"
+	ImportCompleted ImportJobStatus = "Completed"
+	ImportFailed    ImportJobStatus = "Failed"
+)
+
+// ImportJob tracks a bulk product import and its per-row outcome
+type ImportJob struct {
+	ID            uint `gorm:"primaryKey"`
+	Filename      string
+	Format        string          `gorm:"type:varchar(10); not null"`
+	DryRun        bool            `gorm:"not null; default:false"`
+	Status        ImportJobStatus `gorm:"type:varchar(20); default:'Queued'"`
+	TotalRows     int             `gorm:"not null; default:0"`
+	ProcessedRows int             `gorm:"not null; default:0"`
+	CreatedCount  int             `gorm:"not null; default:0"`
+	UpdatedCount  int             `gorm:"not null; default:0"`
+	FailedCount   int             `gorm:"not null; default:0"`
+	Errors        ImportRowErrors
+	Message       string // set when the whole import fails, e.g. an unreadable file
+	CreatedBy     *uint  // nil for imports started from the command line
+	StartedAt     *time.Time
+	FinishedAt    *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	SKU   string `json:"sku,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportRowErrors is stored as JSON
+type ImportRowErrors []ImportRowError
+
+func (e ImportRowErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(e)
+	return string(b), err
+}
+
+func (e *ImportRowErrors) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for ImportRowErrors")
+	}
+	return json.Unmarshal(data, e)
+}
+
+func (ImportRowErrors) GormDataType() string {
+	return "jsonb"
+}
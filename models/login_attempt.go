@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// LoginAttemptCounter holds the failed login count of an account or client IP, for the database store of the login guard
+type LoginAttemptCounter struct {
+	Key         string `gorm:"primaryKey;type:varchar(320)"` // "account:<email>" or "ip:<address>"
+	Failures    int    `gorm:"not null;default:0"`
+	LastFailure time.Time
+	LockedUntil time.Time
+	UpdatedAt   time.Time
+}
+
+// LoginFailure records a failed login attempt for auditing
+type LoginFailure struct {
+	ID        uint   `gorm:"primaryKey"`
+	Email     string `gorm:"not null;index"` // as entered
+	UserID    *uint  `gorm:"index"`          // set when the email belongs to an account
+	IP        string `gorm:"index"`
+	UserAgent string
+	Reason    string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"index"`
+}
@@ -14,10 +14,14 @@ const (
 )
 
 type Order struct {
-	ID        uint        `gorm:"primaryKey"`
-	UserID    uint        `gorm:"not null"`
-	Products  []OrderItem `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
-	Status    OrderStatus `gorm:"type:varchar(20); default:'Pending'"`
+	ID        uint                 `gorm:"primaryKey"`
+	UserID    uint                 `gorm:"not null;index"`
+	User      User                 `gorm:"foreignKey:UserID"`
+	Products  []OrderItem          `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+	History   []OrderStatusHistory `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+	Status    OrderStatus          `gorm:"type:varchar(20); default:'Pending'"`
+	Total     float64              `gorm:"not null; default:0"` // sum of item price * quantity at the time of ordering
+	Version   uint                 `gorm:"not null; default:1"` // bumped on every status change, used for optimistic locking
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -29,4 +33,19 @@ type OrderItem struct {
 	Product   Product `gorm:"foreignKey:ProductID"`
 	Quantity  int     `gorm:"not null; default:1"`
 	Price     float64 `gorm:"not null"` // capture price at the time of ordering
+
+	// Snapshot of the product at the time of ordering, so later edits or deletion don't rewrite the order
+	ProductName        string `gorm:"not null; default:''"`
+	ProductSKU         string
+	ProductDescription string
+	ProductAttributes  Attributes
+}
+
+// OrderStatusHistory records every status an order has been in, and who put it there
+type OrderStatusHistory struct {
+	ID        uint        `gorm:"primaryKey"`
+	OrderID   uint        `gorm:"not null;index"`
+	Status    OrderStatus `gorm:"type:varchar(20); not null"`
+	ChangedBy uint        `gorm:"not null"` // user ID of the customer or admin making the change
+	CreatedAt time.Time
 }
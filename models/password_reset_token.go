@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PasswordResetToken lets a user set a new password once. Only its SHA-256 hash is stored.
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"uniqueIndex; not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+type PriceKind string
+
+const (
+	PriceRegular PriceKind = "regular"
+	PriceSale    PriceKind = "sale"
+)
+
+// Where a recorded price change came from
+const (
+	PriceSourceAdmin    = "admin"
+	PriceSourceImport   = "import"
+	PriceSourceSchedule = "schedule"
+)
+
+// PriceHistory records one change of a product's regular or sale price.
+// A nil OldPrice means the price was first set, a nil NewPrice that a sale ended.
+type PriceHistory struct {
+	ID        uint      `gorm:"primaryKey"`
+	ProductID uint      `gorm:"not null;index"`
+	Kind      PriceKind `gorm:"type:varchar(10); not null"`
+	OldPrice  *float64
+	NewPrice  *float64
+	Source    string `gorm:"type:varchar(20); not null"`
+	ChangedBy *uint  // admin who made or scheduled the change, if known
+	CreatedAt time.Time
+}
+
+type ScheduledPriceStatus string
+
+const (
+	ScheduleScheduled ScheduledPriceStatus = "Scheduled" // waiting for StartsAt
+	ScheduleActive    ScheduledPriceStatus = "Active"    // sale running until EndsAt
+	ScheduleApplied   ScheduledPriceStatus = "Applied"   // regular price has taken effect
+	ScheduleEnded     ScheduledPriceStatus = "Ended"     // sale is over or was replaced
+	ScheduleCancelled ScheduledPriceStatus = "Cancelled"
+)
+
+// ScheduledPrice is a future price change. Regular prices replace Product.Price at StartsAt;
+// sale prices set Product.SalePrice from StartsAt until the optional EndsAt.
+type ScheduledPrice struct {
+	ID        uint                 `gorm:"primaryKey"`
+	ProductID uint                 `gorm:"not null;index"`
+	Kind      PriceKind            `gorm:"type:varchar(10); not null"`
+	Price     float64              `gorm:"not null"`
+	StartsAt  time.Time            `gorm:"not null;index"`
+	EndsAt    *time.Time           `gorm:"index"`
+	Status    ScheduledPriceStatus `gorm:"type:varchar(20); default:'Scheduled'; index"`
+	CreatedBy uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -1,12 +1,28 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Product struct {
 	ID          uint   `gorm:"primaryKey"`
+	SKU         string `gorm:"index:idx_products_sku,unique,where:sku <> ''"`
+	GTIN        string // global trade item number (UPC/EAN/ISBN), used by shopping feeds
 	Name        string `gorm:"not null"`
 	Description string
-	Price       float64 `gorm:"not null"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	Attributes  Attributes
+	Images      []ProductImage `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE"`
+	Price       float64        `gorm:"not null"`
+	SalePrice   *float64       // set while a scheduled sale is running
+	Version     uint           `gorm:"not null;default:1"` // bumped on every update, used for optimistic locking
+
+	// Aggregated from approved reviews
+	RatingAverage float64 `gorm:"not null;default:0"`
+	RatingCount   int     `gorm:"not null;default:0"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"` // set when the product is archived; order items keep referencing it
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ProductImage is an uploaded product photo. The original and its thumbnails live in storage;
+// only the storage key of the original is kept here, thumbnail keys are derived from it.
+type ProductImage struct {
+	ID        uint   `gorm:"primaryKey"`
+	ProductID uint   `gorm:"not null;index"`
+	Key       string `gorm:"not null"`
+	Format    string `gorm:"type:varchar(10); not null"` // jpeg, png or gif
+	Width     int
+	Height    int
+	AltText   string
+	Position  int `gorm:"not null; default:0"` // images are shown in ascending position
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "Pending"
+	ReviewApproved ReviewStatus = "Approved"
+	ReviewRejected ReviewStatus = "Rejected"
+)
+
+// Review is a customer's rating of a product they bought. Only approved reviews are shown and counted.
+type Review struct {
+	ID          uint `gorm:"primaryKey"`
+	ProductID   uint `gorm:"not null;uniqueIndex:idx_reviews_product_user"`
+	UserID      uint `gorm:"not null;uniqueIndex:idx_reviews_product_user"`
+	Rating      int  `gorm:"not null"` // 1 to 5 stars
+	Title       string
+	Body        string
+	Status      ReviewStatus `gorm:"type:varchar(20); default:'Pending'; index"`
+	ModeratedBy *uint
+	ModeratedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Role is a named set of permissions given to staff members
+type Role struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"uniqueIndex; not null"`
+	Description string
+	BuiltIn     bool             `gorm:"not null;default:false"` // built-in roles are kept in sync with the code and can't be edited
+	Permissions []RolePermission `gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type RolePermission struct {
+	RoleID     uint   `gorm:"primaryKey"`
+	Permission string `gorm:"primaryKey;type:varchar(50)"`
+}
+
+// StaffInvitation invites someone to create a staff account with the given roles.
+// Only the SHA-256 hash of the invitation token is stored.
+type StaffInvitation struct {
+	ID         uint   `gorm:"primaryKey"`
+	Email      string `gorm:"not null;index"`
+	TokenHash  string `gorm:"uniqueIndex; not null"`
+	Roles      []Role `gorm:"many2many:staff_invitation_roles"`
+	InvitedBy  uint
+	ExpiresAt  time.Time `gorm:"not null"`
+	AcceptedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
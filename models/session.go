@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Session is one login of a user. Access tokens carry its ID and stop working once it is revoked;
+// it is kept alive by exchanging refresh tokens.
+type Session struct {
+	ID            uint `gorm:"primaryKey"`
+	UserID        uint `gorm:"not null;index"`
+	UserAgent     string
+	IP            string
+	ExpiresAt     time.Time `gorm:"not null"`
+	RevokedAt     *time.Time
+	RevokedReason string
+	LastUsedAt    time.Time
+	CreatedAt     time.Time
+}
+
+// RefreshToken is a single-use token for getting a new access token. Only its SHA-256 hash is stored.
+// Using one rotates it; presenting an already used one revokes the whole session.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	SessionID uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"uniqueIndex; not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use code that stands in for an authenticator app code when the device is lost.
+// Only its SHA-256 hash is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TwoFactorChallenge is handed out by a login with a correct password on an account with two-factor
+// authentication; exchanging it together with a code finishes the login. Only its SHA-256 hash is stored.
+type TwoFactorChallenge struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"uniqueIndex; not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	Attempts  int       `gorm:"not null;default:0"` // wrong codes entered, limited to keep codes from being guessed
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
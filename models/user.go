@@ -1,12 +1,41 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
-	ID        uint   `gorm:"primaryKey"`
-	Email     string `gorm:"uniqueIndex; not null"`
-	Password  string `gorm:"not null"`
-	IsAdmin   bool   `gorm:"default:false"`
+	ID       uint   `gorm:"primaryKey"`
+	Email    string `gorm:"uniqueIndex; not null"`
+	Password string `gorm:"not null"`
+	Roles    []Role `gorm:"many2many:user_roles"` // staff roles; customers have none
+
+	// Profile
+	Name  string
+	Phone string
+
+	PendingEmail       string // new address waiting for verification before it replaces Email
+	EmailVerified      bool   `gorm:"not null;default:false"`
+	EmailVerifiedAt    *time.Time
+	VerificationSentAt *time.Time // when the last verification email went out, for resend throttling
+
+	// Two-factor authentication: TOTPSecret is set during enrolment and in use once TOTPEnabledAt is set
+	TOTPSecret    string
+	TOTPEnabledAt *time.Time
+	TOTPLastStep  int64 // time step of the last accepted code, so each code works only once
+
+	// Disabled accounts can't log in and their tokens are rejected
+	DisabledAt     *time.Time
+	DisabledReason string
+	// Set by an admin to make the user choose a new password through the reset flow before logging in again
+	MustResetPassword bool `gorm:"not null;default:false"`
+
+	// Price list the user buys from, if any
+	CustomerGroupID *uint `gorm:"index"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"` // deleted accounts are anonymized but kept for their orders
 }
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Wishlist is a named list of products a user wants to keep track of.
+// Setting ShareToken makes it readable by anyone holding the token.
+type Wishlist struct {
+	ID         uint    `gorm:"primaryKey"`
+	UserID     uint    `gorm:"not null;index"`
+	Name       string  `gorm:"not null"`
+	ShareToken *string `gorm:"uniqueIndex"`
+	Items      []WishlistItem
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type WishlistItem struct {
+	ID         uint    `gorm:"primaryKey"`
+	WishlistID uint    `gorm:"not null;uniqueIndex:idx_wishlist_items_product"`
+	ProductID  uint    `gorm:"not null;uniqueIndex:idx_wishlist_items_product"`
+	Product    Product `gorm:"foreignKey:ProductID"`
+	Quantity   int     `gorm:"not null;default:1"`
+	CreatedAt  time.Time
+}
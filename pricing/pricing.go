@@ -0,0 +1,51 @@
+// Package pricing works out what a customer pays per unit of a product,
+// taking customer group price lists and quantity breaks into account.
+package pricing
+
+import (
+	"github.com/Emibrown/E-commerce-API/models"
+	"gorm.io/gorm"
+)
+
+// UnitPrice picks the price for quantity units of a product from its tiers.
+//
+// A tier applies once the order reaches its minimum quantity, if it is for everyone or for the
+// customer's group. The cheapest of the applicable tiers and the product's base price is used,
+// so a group price list can never make a customer pay more than the public price.
+func UnitPrice(base float64, tiers []models.ProductPriceTier, groupID *uint, quantity int) float64 {
+	price := base
+	for _, t := range tiers {
+		if quantity < t.MinQuantity {
+			continue
+		}
+		if t.CustomerGroupID != nil && (groupID == nil || *t.CustomerGroupID != *groupID) {
+			continue
+		}
+		if t.Price < price {
+			price = t.Price
+		}
+	}
+	return price
+}
+
+// Resolve loads the product's tiers that could apply to groupID and returns the unit price for quantity.
+// A running sale price is used instead whenever it is lower.
+func Resolve(db *gorm.DB, product models.Product, groupID *uint, quantity int) (float64, error) {
+	query := db.Where("product_id = ?", product.ID)
+	if groupID != nil {
+		query = query.Where("customer_group_id IS NULL OR customer_group_id = ?", *groupID)
+	} else {
+		query = query.Where("customer_group_id IS NULL")
+	}
+
+	var tiers []models.ProductPriceTier
+	if err := query.Find(&tiers).Error; err != nil {
+		return 0, err
+	}
+
+	price := UnitPrice(product.Price, tiers, groupID, quantity)
+	if product.SalePrice != nil && *product.SalePrice < price {
+		price = *product.SalePrice
+	}
+	return price, nil
+}
@@ -0,0 +1,181 @@
+package pricing
+
+import (
+	"errors"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Emibrown/E-commerce-API/models"
+	"gorm.io/gorm"
+)
+
+const defaultSchedulerInterval = time.Minute
+
+// ErrAlreadyHandled means the schedule's status changed before it could be applied,
+// e.g. another scheduler run or an admin got to it first
+var ErrAlreadyHandled = errors.New("scheduled price already handled")
+
+// RecordChange adds a price history entry for a product, unless the price didn't actually change
+func RecordChange(db *gorm.DB, productID uint, kind models.PriceKind, oldPrice, newPrice *float64, source string, changedBy *uint) error {
+	if oldPrice != nil && newPrice != nil && *oldPrice == *newPrice {
+		return nil
+	}
+	if oldPrice == nil && newPrice == nil {
+		return nil
+	}
+
+	return db.Create(&models.PriceHistory{
+		ProductID: productID,
+		Kind:      kind,
+		OldPrice:  oldPrice,
+		NewPrice:  newPrice,
+		Source:    source,
+		ChangedBy: changedBy,
+	}).Error
+}
+
+// StartScheduler applies due scheduled prices in the background, checking every
+// PRICE_SCHEDULER_INTERVAL (default 1m)
+func StartScheduler(db *gorm.DB) {
+	interval := defaultSchedulerInterval
+	if v := os.Getenv("PRICE_SCHEDULER_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Printf("Invalid PRICE_SCHEDULER_INTERVAL %q, using %s", v, defaultSchedulerInterval)
+		} else {
+			interval = d
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := ApplyDue(db, time.Now()); err != nil {
+				log.Printf("Applying scheduled prices failed: %v", err)
+			} else if n > 0 {
+				log.Printf("Applied %d scheduled price changes", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// ApplyDue starts every scheduled price whose StartsAt has passed and ends every running sale
+// whose EndsAt has passed. It returns how many schedules changed state.
+func ApplyDue(db *gorm.DB, now time.Time) (int, error) {
+	applied := 0
+
+	var due []models.ScheduledPrice
+	err := db.Where("status = ? AND starts_at <= ?", models.ScheduleScheduled, now).
+		Order("starts_at ASC, id ASC").
+		Find(&due).Error
+	if err != nil {
+		return applied, err
+	}
+	for _, s := range due {
+		err := db.Transaction(func(tx *gorm.DB) error { return start(tx, s) })
+		if errors.Is(err, ErrAlreadyHandled) {
+			continue
+		}
+		if err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	var ending []models.ScheduledPrice
+	err = db.Where("status = ? AND ends_at <= ?", models.ScheduleActive, now).
+		Order("ends_at ASC, id ASC").
+		Find(&ending).Error
+	if err != nil {
+		return applied, err
+	}
+	for _, s := range ending {
+		err := db.Transaction(func(tx *gorm.DB) error { return EndSale(tx, s) })
+		if errors.Is(err, ErrAlreadyHandled) {
+			continue
+		}
+		if err != nil {
+			return applied, err
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// start puts a scheduled price into effect on its product
+func start(tx *gorm.DB, s models.ScheduledPrice) error {
+	status := models.ScheduleApplied
+	if s.Kind == models.PriceSale {
+		status = models.ScheduleActive
+	}
+	if err := transition(tx, s, models.ScheduleScheduled, status); err != nil {
+		return err
+	}
+
+	// Archived products are updated too, so they have the right price if they're restored
+	var product models.Product
+	if err := tx.Unscoped().First(&product, s.ProductID).Error; err != nil {
+		return err
+	}
+
+	price := s.Price
+	if s.Kind == models.PriceRegular {
+		if err := updateProduct(tx, product.ID, "price", price); err != nil {
+			return err
+		}
+		return RecordChange(tx, product.ID, models.PriceRegular, &product.Price, &price, models.PriceSourceSchedule, &s.CreatedBy)
+	}
+
+	// A new sale replaces whichever one was running
+	err := tx.Model(&models.ScheduledPrice{}).
+		Where("product_id = ? AND kind = ? AND status = ? AND id <> ?", product.ID, models.PriceSale, models.ScheduleActive, s.ID).
+		Update("status", models.ScheduleEnded).Error
+	if err != nil {
+		return err
+	}
+	if err := updateProduct(tx, product.ID, "sale_price", price); err != nil {
+		return err
+	}
+	return RecordChange(tx, product.ID, models.PriceSale, product.SalePrice, &price, models.PriceSourceSchedule, &s.CreatedBy)
+}
+
+// EndSale stops a running sale and clears the product's sale price
+func EndSale(tx *gorm.DB, s models.ScheduledPrice) error {
+	if err := transition(tx, s, models.ScheduleActive, models.ScheduleEnded); err != nil {
+		return err
+	}
+
+	var product models.Product
+	if err := tx.Unscoped().First(&product, s.ProductID).Error; err != nil {
+		return err
+	}
+	if err := updateProduct(tx, product.ID, "sale_price", nil); err != nil {
+		return err
+	}
+	return RecordChange(tx, product.ID, models.PriceSale, product.SalePrice, nil, models.PriceSourceSchedule, &s.CreatedBy)
+}
+
+// transition moves a schedule from one status to another, failing with ErrAlreadyHandled
+// if its status was changed in the meantime
+func transition(tx *gorm.DB, s models.ScheduledPrice, from, to models.ScheduledPriceStatus) error {
+	result := tx.Model(&models.ScheduledPrice{}).Where("id = ? AND status = ?", s.ID, from).Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlreadyHandled
+	}
+	return nil
+}
+
+// updateProduct sets one price column and bumps the product version, so concurrent edits notice the change
+func updateProduct(tx *gorm.DB, productID uint, column string, value interface{}) error {
+	return tx.Unscoped().Model(&models.Product{}).Where("id = ?", productID).Updates(map[string]interface{}{
+		column:    value,
+		"version": gorm.Expr("version + 1"),
+	}).Error
+}
@@ -0,0 +1,135 @@
+// Package rbac defines the permissions staff members can hold and the built-in roles that bundle them
+package rbac
+
+import (
+	"github.com/Emibrown/E-commerce-API/models"
+	"gorm.io/gorm"
+)
+
+// Permissions checked by the admin API
+const (
+	ManageCatalog   = "catalog:manage"   // products, images, imports/exports, prices
+	ModerateReviews = "reviews:moderate" // approve, reject and delete reviews
+	ReadOrders      = "orders:read"      // list and view every customer's orders
+	ManageOrders    = "orders:manage"    // change order status
+	ReadCustomers   = "customers:read"   // view customer accounts
+	ManageCustomers = "customers:manage" // customer groups and account changes
+	ManageStaff     = "staff:manage"     // invite staff, manage roles
+)
+
+// All lists every permission, in display order
+var All = []string{
+	ManageCatalog,
+	ModerateReviews,
+	ReadOrders,
+	ManageOrders,
+	ReadCustomers,
+	ManageCustomers,
+	ManageStaff,
+}
+
+// SuperAdmin is the built-in role holding every permission
+const SuperAdmin = "super-admin"
+
+// BuiltInRole describes a role that always exists
+type BuiltInRole struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// BuiltInRoles are created, and reset to these permissions, by Seed
+var BuiltInRoles = []BuiltInRole{
+	{
+		Name:        "catalog-manager",
+		Description: "Manages products, prices and reviews",
+		Permissions: []string{ManageCatalog, ModerateReviews},
+	},
+	{
+		Name:        "order-fulfiller",
+		Description: "Processes and ships orders",
+		Permissions: []string{ReadOrders, ManageOrders},
+	},
+	{
+		Name:        "support",
+		Description: "Helps customers with their orders and accounts",
+		Permissions: []string{ReadOrders, ReadCustomers, ModerateReviews},
+	},
+	{
+		Name:        SuperAdmin,
+		Description: "Full access, including staff management",
+		Permissions: All,
+	},
+}
+
+// IsValid reports whether permission is a known permission
+func IsValid(permission string) bool {
+	for _, p := range All {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Seed creates the built-in roles and makes sure they hold exactly their listed permissions
+func Seed(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, builtIn := range BuiltInRoles {
+			var role models.Role
+			err := tx.Where(models.Role{Name: builtIn.Name}).
+				Attrs(models.Role{Description: builtIn.Description}).
+				FirstOrCreate(&role).Error
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Model(&role).Updates(map[string]interface{}{
+				"description": builtIn.Description,
+				"built_in":    true,
+			}).Error; err != nil {
+				return err
+			}
+			if err := SetPermissions(tx, role.ID, builtIn.Permissions); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetPermissions replaces the permissions of a role
+func SetPermissions(tx *gorm.DB, roleID uint, permissions []string) error {
+	if err := tx.Where("role_id = ?", roleID).Delete(&models.RolePermission{}).Error; err != nil {
+		return err
+	}
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	rows := make([]models.RolePermission, 0, len(permissions))
+	for _, p := range permissions {
+		rows = append(rows, models.RolePermission{RoleID: roleID, Permission: p})
+	}
+	return tx.Create(&rows).Error
+}
+
+// UserPermissions returns every permission a user holds through their roles
+func UserPermissions(db *gorm.DB, userID uint) ([]string, error) {
+	var permissions []string
+	err := db.Model(&models.RolePermission{}).
+		Distinct("role_permissions.permission").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Pluck("role_permissions.permission", &permissions).Error
+	return permissions, err
+}
+
+// GrantSuperAdmin gives a user the super-admin role, keeping any roles they already have
+func GrantSuperAdmin(db *gorm.DB, user *models.User) error {
+	var role models.Role
+	if err := db.Where("name = ?", SuperAdmin).First(&role).Error; err != nil {
+		return err
+	}
+	return db.Model(user).Association("Roles").Append(&role)
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/Emibrown/E-commerce-API/controllers"
 	"github.com/Emibrown/E-commerce-API/middlewares"
+	"github.com/Emibrown/E-commerce-API/rbac"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,31 +14,143 @@ func SetupRoutes(r *gin.Engine) {
 	{
 		auth.POST("/register", controllers.Register)
 		auth.POST("/login", controllers.Login)
-		auth.POST("/register-admin", controllers.RegisterAdmin)
+		auth.POST("/login/2fa", controllers.LoginTwoFactor)
+		auth.POST("/refresh", controllers.Refresh)
+		auth.POST("/password-reset", controllers.RequestPasswordReset)
+		auth.POST("/password-reset/confirm", controllers.ConfirmPasswordReset)
+		auth.POST("/verify-email", controllers.VerifyEmail)
+		auth.POST("/invitations/accept", controllers.AcceptStaffInvitation)
 	}
 
+	// Public shared wishlists
+	r.GET("/api/shared-wishlists/:token", controllers.GetSharedWishlist)
+
+	// Public keys access tokens are signed with, for other services verifying them
+	r.GET("/.well-known/jwks.json", controllers.GetJWKS)
+
+	// Public product feed
+	r.GET("/feeds/google-merchant.xml", controllers.GetProductFeed)
+
 	// Protected routes
 	api := r.Group("/api")
 	api.Use(middlewares.AuthMiddleware())
 	{
+		api.POST("/auth/logout", controllers.Logout)
+		api.POST("/auth/verify-email/resend", controllers.ResendVerificationEmail)
+
+		// Account self-service
+		api.GET("/me", controllers.GetMe)
+		api.PATCH("/me", controllers.UpdateMe)
+		api.POST("/me/password", controllers.ChangePassword)
+		api.POST("/me/email", controllers.ChangeEmail)
+		api.POST("/me/2fa/setup", controllers.SetupTwoFactor)
+		api.POST("/me/2fa/enable", controllers.EnableTwoFactor)
+		api.POST("/me/2fa/disable", controllers.DisableTwoFactor)
+		api.POST("/me/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+
 		// Orders (User only)
-		api.POST("/orders", controllers.CreateOrder)
+		api.POST("/orders", middlewares.RequireVerifiedEmailForOrders(), middlewares.Idempotency(), controllers.CreateOrder)
 		api.GET("/orders", controllers.GetOrders)
+		api.GET("/orders/:id", controllers.GetOrderByID)
 		api.PUT("/orders/:id/cancel", controllers.CancelOrder)
 
-		// Admin routes
+		// Wishlists
+		api.POST("/wishlists", controllers.CreateWishlist)
+		api.GET("/wishlists", controllers.GetWishlists)
+		api.GET("/wishlists/:id", controllers.GetWishlistByID)
+		api.PUT("/wishlists/:id", controllers.UpdateWishlist)
+		api.DELETE("/wishlists/:id", controllers.DeleteWishlist)
+		api.POST("/wishlists/:id/items", controllers.AddWishlistItem)
+		api.DELETE("/wishlists/:id/items/:item_id", controllers.RemoveWishlistItem)
+		api.POST("/wishlists/:id/share", controllers.ShareWishlist)
+		api.DELETE("/wishlists/:id/share", controllers.UnshareWishlist)
+		api.POST("/wishlists/:id/order", middlewares.RequireVerifiedEmailForOrders(), middlewares.Idempotency(), controllers.OrderWishlist)
+
+		// Reviews
+		api.GET("/products/:id/reviews", controllers.GetProductReviews)
+		api.POST("/products/:id/reviews", controllers.CreateReview)
+
+		// Admin routes: staff only, each route further limited to a permission.
+		// With REQUIRE_STAFF_2FA, staff must enable two-factor authentication first.
 		admin := api.Group("/admin")
-		admin.Use(middlewares.AdminMiddleware())
+		admin.Use(middlewares.StaffMiddleware(), middlewares.RequireStaffTwoFactor())
 		{
+			manageCatalog := middlewares.RequirePermission(rbac.ManageCatalog)
+			moderateReviews := middlewares.RequirePermission(rbac.ModerateReviews)
+			readOrders := middlewares.RequirePermission(rbac.ReadOrders)
+			manageOrders := middlewares.RequirePermission(rbac.ManageOrders)
+			readCustomers := middlewares.RequirePermission(rbac.ReadCustomers)
+			manageCustomers := middlewares.RequirePermission(rbac.ManageCustomers)
+			manageStaff := middlewares.RequirePermission(rbac.ManageStaff)
+
 			// Product management
-			admin.POST("/products", controllers.CreateProduct)
-			admin.GET("/products", controllers.GetProducts)
-			admin.GET("/products/:id", controllers.GetProductByID)
-			admin.PUT("/products/:id", controllers.UpdateProduct)
-			admin.DELETE("/products/:id", controllers.DeleteProduct)
-
-			// Order status update
-			admin.PUT("/orders/:id/status", controllers.UpdateOrderStatus)
+			admin.POST("/products", manageCatalog, controllers.CreateProduct)
+			admin.GET("/products", manageCatalog, controllers.GetProducts)
+			admin.GET("/products/export", manageCatalog, controllers.ExportProducts)
+			admin.GET("/products/:id", manageCatalog, controllers.GetProductByID)
+			admin.PUT("/products/:id", manageCatalog, controllers.UpdateProduct)
+			admin.PATCH("/products/:id", manageCatalog, controllers.UpdateProduct)
+			admin.DELETE("/products/:id", manageCatalog, controllers.DeleteProduct)
+			admin.POST("/products/:id/restore", manageCatalog, controllers.RestoreProduct)
+
+			// Product images
+			admin.POST("/products/:id/images", manageCatalog, controllers.UploadProductImage)
+			admin.PUT("/products/:id/images/order", manageCatalog, controllers.ReorderProductImages)
+			admin.PATCH("/products/:id/images/:image_id", manageCatalog, controllers.UpdateProductImage)
+			admin.DELETE("/products/:id/images/:image_id", manageCatalog, controllers.DeleteProductImage)
+
+			// Customer groups and tiered pricing
+			admin.POST("/customer-groups", manageCustomers, controllers.CreateCustomerGroup)
+			admin.GET("/customer-groups", manageCustomers, controllers.GetCustomerGroups)
+			admin.PUT("/customer-groups/:id", manageCustomers, controllers.UpdateCustomerGroup)
+			admin.DELETE("/customer-groups/:id", manageCustomers, controllers.DeleteCustomerGroup)
+			admin.PUT("/users/:id/customer-group", manageCustomers, controllers.SetUserCustomerGroup)
+			admin.GET("/products/:id/price-tiers", manageCatalog, controllers.GetProductPriceTiers)
+			admin.POST("/products/:id/price-tiers", manageCatalog, controllers.CreateProductPriceTier)
+			admin.DELETE("/products/:id/price-tiers/:tier_id", manageCatalog, controllers.DeleteProductPriceTier)
+
+			// Price history and scheduled prices
+			admin.GET("/products/:id/price-history", manageCatalog, controllers.GetPriceHistory)
+			admin.GET("/products/:id/scheduled-prices", manageCatalog, controllers.GetScheduledPrices)
+			admin.POST("/products/:id/scheduled-prices", manageCatalog, controllers.SchedulePrice)
+			admin.DELETE("/products/:id/scheduled-prices/:schedule_id", manageCatalog, controllers.CancelScheduledPrice)
+
+			// Review moderation
+			admin.GET("/reviews", moderateReviews, controllers.AdminGetReviews)
+			admin.PUT("/reviews/:id/status", moderateReviews, controllers.ModerateReview)
+			admin.DELETE("/reviews/:id", moderateReviews, controllers.DeleteReview)
+
+			// Bulk product import
+			admin.POST("/product-imports", manageCatalog, controllers.ImportProducts)
+			admin.GET("/product-imports/:id", manageCatalog, controllers.GetImportJob)
+
+			// Order management
+			admin.GET("/orders", readOrders, controllers.AdminGetOrders)
+			admin.GET("/orders/:id", readOrders, controllers.AdminGetOrderByID)
+			admin.PUT("/orders/:id/status", manageOrders, controllers.UpdateOrderStatus)
+
+			// User accounts
+			admin.GET("/users", readCustomers, controllers.AdminGetUsers)
+			admin.GET("/users/:id", readCustomers, controllers.AdminGetUserByID)
+			admin.DELETE("/users/:id", manageCustomers, controllers.DeleteUser)
+			admin.POST("/users/:id/disable", manageCustomers, controllers.DisableUser)
+			admin.POST("/users/:id/enable", manageCustomers, controllers.EnableUser)
+			admin.POST("/users/:id/force-password-reset", manageCustomers, controllers.ForcePasswordReset)
+			admin.POST("/users/:id/reset-2fa", manageStaff, controllers.ResetTwoFactor)
+			admin.POST("/users/:id/unlock", manageCustomers, controllers.UnlockUser)
+			admin.GET("/login-failures", readCustomers, controllers.AdminGetLoginFailures)
+
+			// Staff, roles and invitations
+			admin.GET("/permissions", manageStaff, controllers.GetPermissions)
+			admin.GET("/roles", manageStaff, controllers.GetRoles)
+			admin.POST("/roles", manageStaff, controllers.CreateRole)
+			admin.PUT("/roles/:id", manageStaff, controllers.UpdateRole)
+			admin.DELETE("/roles/:id", manageStaff, controllers.DeleteRole)
+			admin.GET("/staff", manageStaff, controllers.GetStaff)
+			admin.PUT("/users/:id/roles", manageStaff, controllers.SetUserRoles)
+			admin.POST("/staff/invitations", manageStaff, controllers.InviteStaff)
+			admin.GET("/staff/invitations", manageStaff, controllers.GetStaffInvitations)
+			admin.DELETE("/staff/invitations/:id", manageStaff, controllers.RevokeStaffInvitation)
 		}
 	}
 }
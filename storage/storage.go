@@ -0,0 +1,55 @@
+// Package storage saves uploaded files (product images and their thumbnails) to a pluggable backend
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Storage stores objects under slash-separated keys and knows the public URL of each object
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// Default is the storage backend used by the API, set up by Init
+var Default Storage
+
+// Init picks the storage backend from the STORAGE_DRIVER environment variable ("local" or "s3")
+func Init() error {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		publicURL := os.Getenv("STORAGE_PUBLIC_URL")
+		if publicURL == "" {
+			publicURL = LocalServePath
+		}
+		local, err := NewLocal(dir, publicURL)
+		if err != nil {
+			return err
+		}
+		Default = local
+	case "s3":
+		s3, err := NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("STORAGE_PUBLIC_URL"),
+		})
+		if err != nil {
+			return err
+		}
+		Default = s3
+	default:
+		return fmt.Errorf("unknown STORAGE_DRIVER %q (expected local or s3)", driver)
+	}
+	return nil
+}